@@ -2,18 +2,13 @@ package main
 
 import (
 	"encoding/csv"
-	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
-	"unicode/utf8"
 
-	"github.com/tealeg/xlsx"
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
@@ -21,52 +16,181 @@ import (
 
 func failOnError(err error) {
 	if err != nil {
-		log.Fatal("Error:", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		logError("Error", "err", err)
+		exit(1)
 	}
 }
 
 func main() {
-	flag.Parse()
+	// ログは出力先が決まってから書く（変換しない場合は exe と同じフォルダ）
+	logInfo("Start", "args", strings.Join(os.Args[1:], " "))
 
-	// ログファイル準備
-	logfile, err := os.OpenFile("./log.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
-	failOnError(err)
-	defer logfile.Close()
+	// サブコマンドが無ければ変換する（exe にファイルをドロップした場合）
+	runCommand(os.Args[1:])
+
+	logInfo("Finish")
+	closeLog()
+
+}
+
+// runConvert は convert サブコマンド（抽出データを変換して出力ファイルを作る）
+// 抽出データ・フォルダを複数指定した場合は１つにまとめて変換する（-each なら１つずつ別の出力フォルダ）
+func runConvert(args []string) {
+	fs := newFlags("convert")
+	format := fs.String("format", "", "出力形式 xlsx/csv/fixed（検診ごとは kenshin=csv,gastric=fixed）")
+	encode := fs.String("encoding", "", "csv・固定長の文字コード sjis/utf8（検診ごとの指定は -format と同じ）")
+	quote := fs.String("quote", "", "csv の囲み all/none（検診ごとの指定は -format と同じ）")
+	newline := fs.String("newline", "", "csv・固定長の改行 crlf/lf（検診ごとの指定は -format と同じ）")
+	record := fs.String("json", "", "健診結果の出力 json/ndjson")
+	only, profile := fs.layoutFlags()
+	each := fs.Bool("each", false, "抽出データごとに別の出力フォルダに変換する")
+	out := fs.String("out", "", "出力先のフォルダ（省略時は抽出データと同じフォルダ）")
+	stats := fs.String("stats", "", "健康統計を出力する xlsx/csv")
+	overwrite := fs.String("overwrite", "", "同じ名前の出力があるとき fail/suffix/replace")
+	followup := fs.Bool("followup", false, "要精密検査・要再検のリストを出力する")
+	notices := fs.Bool("notice", false, "受診者ごとの結果通知を出力する")
+	args = fs.parse(args)
+
+	conf.Output = applyOutputFlag(conf.Output, *format, func(oc *OutputConfig, v string) { oc.Format = v })
+	conf.Output = applyOutputFlag(conf.Output, *encode, func(oc *OutputConfig, v string) { oc.Encoding = v })
+	conf.Output = applyOutputFlag(conf.Output, *quote, func(oc *OutputConfig, v string) { oc.Quote = v })
+	conf.Output = applyOutputFlag(conf.Output, *newline, func(oc *OutputConfig, v string) { oc.Newline = v })
+	if *record != "" {
+		conf.Record.Format = *record
+	}
+	if *each {
+		conf.Input.Each = true
+	}
+	if *out != "" {
+		conf.Naming.Out = *out
+	}
+	if *stats != "" {
+		conf.Stats.Enabled = true
+		conf.Stats.Format = *stats
+	}
+	if *overwrite != "" {
+		conf.Naming.Overwrite = *overwrite
+	}
+	if *followup {
+		conf.Followup.Enabled = true
+	}
+	if *notices {
+		conf.Notice.Enabled = true
+	}
+	selected := chosenLayouts(*only, *profile)
+	paths := inputArgs(args)
+
+	var results []runResult
+	var reportPath string
+	if conf.Input.Each && len(paths) > 1 {
+		// ログ・実行結果などは -out のフォルダ、無ければ exe と同じフォルダ（抽出データのフォルダには書かない）
+		outBase := conf.Naming.Out
+		if outBase == "" {
+			outBase = filepath.Dir(configPath())
+		}
+		openLog(outBase)
+
+		// 抽出データごとに変換する（チェック結果なども出力フォルダごと）
+		for _, path := range paths {
+			checks, missings, shortMeals, exclusions = nil, nil, nil, nil
+			kiouUnknowns = map[string]int{}
+			results = append(results, convertInput([]string{path}, selected, dirCreate(path, true)))
+		}
+		reportPath = outBase + string(filepath.Separator)
+	} else {
+		// ログは出力フォルダに書く
+		filePath := dirCreate(paths[0], false)
+		openLog(filePath)
+		results = append(results, convertInput(paths, selected, filePath))
+		reportPath = results[0].OutDir
+	}
+
+	// 前年度受診・今年度未受診の人（すべての抽出データを履歴に登録してから）
+	if conf.History.Enabled && len(selected) == len(layouts()) {
+		var exams []examRecord
+		for _, r := range results {
+			exams = append(exams, r.exams...)
+		}
+		db := openHistory()
+		writeSkipped(reportPath, db, exams)
+		db.Close()
+	}
 
-	log.SetOutput(logfile)
+	// 複数の抽出データの実行結果
+	if len(paths) > 1 {
+		writeRunReport(reportPath, results)
+	}
 
-	log.Print("Start\r\n")
+	printOutputs()
+}
 
+// convertInput は抽出データを読み込んで出力フォルダに変換する
+// filePath は出力フォルダ（dirCreate の戻り値）
+func convertInput(paths []string, selected []Layout, filePath string) runResult {
 	// ファイルを読み込んで二次元配列に入れる
-	filePath := flag.Arg(0)
-	records := readfile(filePath)
+	records := readInput(paths...)
+
+	// データの変換 健康診断・各がん検診・骨密度
+	outRecs := map[string][]outRow{}
+	for _, l := range selected {
+		outRecs[l.Name()] = writeLayout(filePath, l, records)
+	}
+
+	// 特定健診XML（健診データと同じ内容を標準様式で出力する）
+	if conf.XML.Enabled && len(outRecs["健診"]) > 0 {
+		writeXML(filePath, outRecs["健診"])
+	}
 
-	// 出力するフォルダを作成
-	filePath = dirCreate(filePath)
+	// 健診結果（社内の分析・健康管理用）
+	exams := buildExamRecords(outRecs)
+	if conf.Record.Format == "json" || conf.Record.Format == "ndjson" {
+		writeExamRecords(filePath, exams, conf.Record.Format)
+	}
 
-	// データの変換 健康診断
-	dataConversion(filePath, records)
+	// 健診結果の履歴
+	// 一部の検診だけの変換ではがん検診の結果が欠けるため登録しない
+	if conf.History.Enabled && len(selected) == len(layouts()) {
+		db := openHistory()
+		storeHistory(db, exams, historyInput(paths))
+		db.Close()
+	} else if conf.History.Enabled {
+		logInfo("一部の検診だけの変換のため履歴に登録しません")
+	}
+
+	// 健康統計（衛生委員会向け）
+	if conf.Stats.Enabled && len(outRecs["健診"]) > 0 {
+		writeStats(filePath, exams)
+	}
 
-	// データの変換 胃がん
-	gastricConversion(filePath, records)
+	// 要精密検査・要再検（受診勧奨と再受診の確認）
+	if conf.Followup.Enabled {
+		writeFollowup(filePath, exams, records)
+	}
 
-	// データの変換 子宮がん
-	uterineConversion(filePath, records)
+	// 個人結果通知（所属ごとのフォルダ）
+	if conf.Notice.Enabled && len(outRecs["健診"]) > 0 {
+		writeNotices(filePath, exams, records)
+	}
 
-	// データの変換 乳がん
-	breastConversion(filePath, records)
+	// 既往歴の辞書に無い病名（病名ごとの件数）
+	logKiouUnknowns()
 
-	// データの変換 前立腺がん
-	prostateConversion(filePath, records)
+	// 必須項目の未実施（③必須項目・未受診理由入力シート）
+	writeRequired(filePath)
 
-	// データの変換 マンモグラフィー
-	mmgConversion(filePath, records)
+	// 対象者・入力内容のチェック結果
+	writeChecks(filePath)
 
-	// データの変換 骨密度
-	dexaConversion(filePath, records)
+	r := runResult{Inputs: paths, OutDir: filePath, Checks: len(checks), Missings: len(missings), exams: exams}
+	for _, l := range selected {
+		r.Counts = append(r.Counts, layoutCount{l.Name(), len(outRecs[l.Name()])})
+	}
 
-	log.Print("Finish !\r\n")
+	// 集計（提出書類の表紙）
+	writeSummary(filePath, r, selected, outRecs, records)
 
+	return r
 }
 
 func readfile(filename string) [][]string {
@@ -94,1270 +218,891 @@ func readfile(filename string) [][]string {
 	return readrecords
 }
 
-func dirCreate(path string) string {
-	day := time.Now()
-	outDir, _ := filepath.Split(path)
-	outDirPlus := outDir + "/松英会職員健診データ" + day.Format("20060102")
-
-	if err := os.Mkdir(outDirPlus, 0777); err != nil {
-		log.Print(outDirPlus + "\r\n")
-		log.Print("出力先のディレクトリを作成できませんでした\r\n")
-		return outDir
-	} else {
-		return outDirPlus + "/"
-	}
-}
-
-func dataConversion(filename string, inRecs [][]string) {
-	// var excelFile *xlsx.File
-	// var sheet *xlsx.Sheet
-	var vcell *xlsx.Cell
-	var cell string
+// readInput は抽出データを読み込み、設定があれば複数日受診を統合する
+// 複数のファイルは１つにまとめる。別のファイルに同じ受診（記号・番号・受診日）があれば
+// 同じ内容なら除き、内容が違えば後のファイルの行を使ってチェック結果に出力する
+func readInput(paths ...string) [][]string {
+	var records [][]string
+	type origin struct{ row, file int }
+	seen := map[string]origin{} // 受診 → records の行と抽出データ
+
+	for F, path := range paths {
+		recs := readfile(path)
+		in := inputFile{Path: path}
+		switch {
+		case len(recs) == 0:
+		case records == nil:
+			records = [][]string{recs[0]}
+		case len(recs[0]) != len(records[0]):
+			notice(fmt.Sprintf("列の数が違うため読み込みません %s（%d列 最初の抽出データは%d列）", path, len(recs[0]), len(records[0])))
+			recs = nil
+		}
 
-	recLen := 98 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
+		for J := 1; J < len(recs); J++ {
+			rec := recs[J]
+			in.Rows++
 
-	day := time.Now()
+			//　保険証番号が空欄は、データ出力対象外
+			if rec[6] == "" {
+				addExclusion("入力", "証番号が空欄")
+				records = append(records, rec)
+				continue
+			}
 
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員健診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
+			key := rec[5] + "-" + rec[6] + "/" + rec[4]
+			o, ok := seen[key]
+			switch {
+			case !ok || o.file == F:
+				seen[key] = origin{len(records), F}
+				records = append(records, rec)
+			case strings.Join(records[o.row], "\t") == strings.Join(rec, "\t"):
+				addExclusion("入力", "別の抽出データと同じ受診")
+				in.Same++
+			default:
+				addCheckKey("入力", rec[5]+"-"+rec[6], rec[7], "受診日", rec[4], "複数の抽出データに同じ受診があり内容が違う（"+filepath.Base(path)+"を使用）")
+				records[o.row] = rec
+				seen[key] = origin{o.row, F}
+				in.Replaced++
+			}
+		}
 
-	//タイトル行
-	cRec[0] = "実施健診機関CD"
-	cRec[1] = "健診種別CD"
-	cRec[2] = "受診日"
-	cRec[3] = "事業所記号"
-	cRec[4] = "証番号"
-	cRec[5] = "資格区分"
-	cRec[6] = "続柄"
-	cRec[7] = "枝番"
-	cRec[8] = "漢字氏名"
-	cRec[9] = "カナ氏名"
-	cRec[10] = "性別"
-	cRec[11] = "生年月日"
-	cRec[12] = "OP　０１"
-	cRec[13] = "OP　０２"
-	cRec[14] = "OP　０３"
-	cRec[15] = "OP　０４"
-	cRec[16] = "OP　０５"
-	cRec[17] = "OP　０６"
-	cRec[18] = "OP　０７"
-	cRec[19] = "OP　０８"
-	cRec[20] = "OP　０９"
-	cRec[21] = "OP　１０"
-	cRec[22] = "OP 11"
-	cRec[23] = "請求区分"
-	cRec[24] = "健診金額"
-	cRec[25] = "法定金額"
-	cRec[26] = "請求金額"
-	cRec[27] = "支払先CD"
-	cRec[28] = "身長"
-	cRec[29] = "体重"
-	cRec[30] = "BMI"
-	cRec[31] = "腹囲"
-	cRec[32] = "身体検査判定"
-	cRec[33] = "血圧（収縮期）"
-	cRec[34] = "血圧（拡張期）"
-	cRec[35] = "空腹時中性脂肪"
-	cRec[36] = "随時中性脂肪"
-	cRec[37] = "HDL・CO"
-	cRec[38] = "LDL・CO"
-	cRec[39] = "Non・HDLCO"
-	cRec[40] = "AST(GOT)"
-	cRec[41] = "ALT(GPT)"
-	cRec[42] = "γ・GTP"
-	cRec[43] = "空腹時血糖"
-	cRec[44] = "HｂA1ｃ"
-	cRec[45] = "随時血糖"
-	cRec[46] = "採血時間"
-	cRec[47] = "尿糖"
-	cRec[48] = "尿蛋白"
-	cRec[49] = "未実施の場合その理由"
-	cRec[50] = "白血球数"
-	cRec[51] = "赤血球数"
-	cRec[52] = "血色素量"
-	cRec[53] = "ヘマトクリット"
-	cRec[54] = "心電図所見"
-	cRec[55] = "眼底精密所見"
-	cRec[56] = "血清クレアチニン"
-	cRec[57] = "eGFR"
-	cRec[58] = "HBｓ抗原"
-	cRec[59] = "HBs抗体"
-	cRec[60] = "HCV抗体価精密測定"
-	cRec[61] = "胸部X線検査判定"
-	cRec[62] = "尿酸値"
-	cRec[63] = "腹部超音波検査判定"
-	cRec[64] = "便潜血"
-	cRec[65] = "総合判定"
-	cRec[66] = "メタボリック判定"
-	cRec[67] = "医師の診断"
-	cRec[68] = "医師名"
-	cRec[69] = "既往歴"
-	cRec[70] = "具体的な既往歴"
-	cRec[71] = "自覚症状"
-	cRec[72] = "自覚症状所見"
-	cRec[73] = "他覚症状"
-	cRec[74] = "他覚症状所見"
-	cRec[75] = "保健指導レベル"
-	cRec[76] = "服薬・血圧"
-	cRec[77] = "服薬・血糖"
-	cRec[78] = "服薬・コレステロール"
-	cRec[79] = "脳卒中"
-	cRec[80] = "心臓病"
-	cRec[81] = "慢性腎臓病"
-	cRec[82] = "貧血"
-	cRec[83] = "たばこ"
-	cRec[84] = "体重１０㌔増"
-	cRec[85] = "汗かく運動"
-	cRec[86] = "歩行１時間以上"
-	cRec[87] = "歩く速度"
-	cRec[88] = "食事噛む状態"
-	cRec[89] = "食べる速度"
-	cRec[90] = "就寝前食事"
-	cRec[91] = "間食"
-	cRec[92] = "朝食抜き"
-	cRec[93] = "お酒・頻度"
-	cRec[94] = "お酒・量"
-	cRec[95] = "睡眠"
-	cRec[96] = "改善の意思"
-	cRec[97] = "指導受診歴"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
+		inputs = append(inputs, in)
+		logInfo("抽出データ", "path", path, "rows", in.Rows, "same", in.Same, "replaced", in.Replaced)
 	}
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
+	// 複数日受診の統合
+	if conf.Merge.Enabled {
+		var conflicts []mergeConflict
+		records, conflicts = mergeVisits(records, conf.Merge.Jushinbi)
+		if len(conflicts) > 0 {
+			logWarn("受診日統合 値の不一致", "count", len(conflicts))
 		}
+	}
+	return records
+}
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			// 0.実施健診機関CD
-			cRec[0] = "415201"
-
-			// 1.健診種別CD
-			if kazokuCheck(inRecs[J][3]) {
-				cRec[1] = "2000" // 家族
-			} else {
-				cRec[1] = "1000" // 本人
-			}
-
-			// 2.受診日
-			cRec[2] = strings.Replace((inRecs[J][4]), "-", "/", -1)
-
-			// 3.事業所記号
-			cRec[3] = inRecs[J][5]
-
-			// 4.証番号
-			cRec[4] = inRecs[J][6]
+// kenshinLayout は健診データ（特定健診）のレイアウト
+type kenshinLayout struct{}
 
-			// 5.資格区分
-			if kazokuCheck(inRecs[J][3]) {
-				cRec[5] = "1" // 家族
-			} else {
-				cRec[5] = "0" // 本人
-			}
+func (kenshinLayout) Key() string {
+	return "kenshin"
+}
 
-			// 6.続柄
-			cRec[6] = ""
+// Sheets は既往歴のシート（設定の kiou.export が true の場合だけ）
+func (kenshinLayout) Sheets(rows []outRow) []sheetData {
+	if !conf.Kiou.Export {
+		return nil
+	}
+	return []sheetData{kiouSheet(rows)}
+}
 
-			// 7.枝番
-			cRec[7] = ""
+func (kenshinLayout) Source() string {
+	return "11～42列 計測・検査値・44～65列 判定・100列 医師名・101～138列 既往歴・症状・139～160列 質問票"
+}
 
-			// 8.漢字氏名
-			cRec[8] = ""
+func (kenshinLayout) Name() string {
+	return "健診"
+}
 
-			// 9.カナ氏名
-			cRec[9] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
+func (kenshinLayout) Columns() []Column {
+	cRec := make([]Column, 98) //出力する項目数
 
-			// 10.性別
-			cRec[10] = sei(inRecs[J][8])
+	//タイトル行
+	cRec[0] = textCol("実施健診機関CD")
+	cRec[1] = textCol("健診種別CD")
+	cRec[2] = dateCol("受診日")
+	cRec[3] = textCol("事業所記号")
+	cRec[4] = textCol("証番号")
+	cRec[5] = textCol("資格区分")
+	cRec[6] = textCol("続柄")
+	cRec[7] = textCol("枝番")
+	cRec[8] = textCol("漢字氏名")
+	cRec[9] = textCol("カナ氏名")
+	cRec[10] = textCol("性別")
+	cRec[11] = dateCol("生年月日")
+	cRec[12] = textCol("OP　０１")
+	cRec[13] = textCol("OP　０２")
+	cRec[14] = textCol("OP　０３")
+	cRec[15] = textCol("OP　０４")
+	cRec[16] = textCol("OP　０５")
+	cRec[17] = textCol("OP　０６")
+	cRec[18] = textCol("OP　０７")
+	cRec[19] = textCol("OP　０８")
+	cRec[20] = textCol("OP　０９")
+	cRec[21] = textCol("OP　１０")
+	cRec[22] = textCol("OP 11")
+	cRec[23] = textCol("請求区分")
+	cRec[24] = numberCol("健診金額", 0)
+	cRec[25] = numberCol("法定金額", 0)
+	cRec[26] = numberCol("請求金額", 0)
+	cRec[27] = textCol("支払先CD")
+	cRec[28] = numberCol("身長", 1)
+	cRec[29] = numberCol("体重", 1)
+	cRec[30] = numberCol("BMI", 1)
+	cRec[31] = numberCol("腹囲", 1)
+	cRec[32] = textCol("身体検査判定")
+	cRec[33] = numberCol("血圧（収縮期）", 0)
+	cRec[34] = numberCol("血圧（拡張期）", 0)
+	cRec[35] = numberCol("空腹時中性脂肪", 0)
+	cRec[36] = numberCol("随時中性脂肪", 0)
+	cRec[37] = numberCol("HDL・CO", 0)
+	cRec[38] = numberCol("LDL・CO", 0)
+	cRec[39] = numberCol("Non・HDLCO", 0)
+	cRec[40] = numberCol("AST(GOT)", 0)
+	cRec[41] = numberCol("ALT(GPT)", 0)
+	cRec[42] = numberCol("γ・GTP", 0)
+	cRec[43] = numberCol("空腹時血糖", 0)
+	cRec[44] = numberCol("HｂA1ｃ", 1)
+	cRec[45] = numberCol("随時血糖", 0)
+	cRec[46] = textCol("採血時間")
+	cRec[47] = textCol("尿糖")
+	cRec[48] = textCol("尿蛋白")
+	cRec[49] = textCol("未実施の場合その理由")
+	cRec[50] = numberCol("白血球数", 0)
+	cRec[51] = numberCol("赤血球数", 0)
+	cRec[52] = numberCol("血色素量", 1)
+	cRec[53] = numberCol("ヘマトクリット", 1)
+	cRec[54] = textCol("心電図所見")
+	cRec[55] = textCol("眼底精密所見")
+	cRec[56] = numberCol("血清クレアチニン", 2)
+	cRec[57] = numberCol("eGFR", 1)
+	cRec[58] = textCol("HBｓ抗原")
+	cRec[59] = textCol("HBs抗体")
+	cRec[60] = textCol("HCV抗体価精密測定")
+	cRec[61] = textCol("胸部X線検査判定")
+	cRec[62] = numberCol("尿酸値", 1)
+	cRec[63] = textCol("腹部超音波検査判定")
+	cRec[64] = textCol("便潜血")
+	cRec[65] = textCol("総合判定")
+	cRec[66] = textCol("メタボリック判定")
+	cRec[67] = textCol("医師の診断")
+	cRec[68] = textCol("医師名")
+	cRec[69] = textCol("既往歴")
+	cRec[70] = textCol("具体的な既往歴")
+	cRec[71] = textCol("自覚症状")
+	cRec[72] = textCol("自覚症状所見")
+	cRec[73] = textCol("他覚症状")
+	cRec[74] = textCol("他覚症状所見")
+	cRec[75] = textCol("保健指導レベル")
+	cRec[76] = textCol("服薬・血圧")
+	cRec[77] = textCol("服薬・血糖")
+	cRec[78] = textCol("服薬・コレステロール")
+	cRec[79] = textCol("脳卒中")
+	cRec[80] = textCol("心臓病")
+	cRec[81] = textCol("慢性腎臓病")
+	cRec[82] = textCol("貧血")
+	cRec[83] = textCol("たばこ")
+	cRec[84] = textCol("体重１０㌔増")
+	cRec[85] = textCol("汗かく運動")
+	cRec[86] = textCol("歩行１時間以上")
+	cRec[87] = textCol("歩く速度")
+	cRec[88] = textCol("食事噛む状態")
+	cRec[89] = textCol("食べる速度")
+	cRec[90] = textCol("就寝前食事")
+	cRec[91] = textCol("間食")
+	cRec[92] = textCol("朝食抜き")
+	cRec[93] = textCol("お酒・頻度")
+	cRec[94] = textCol("お酒・量")
+	cRec[95] = textCol("睡眠")
+	cRec[96] = textCol("改善の意思")
+	cRec[97] = textCol("指導受診歴")
+	return cRec
+}
 
-			// 11.生年月日
-			cRec[11] = WaToSeireki(inRecs[J][9])
+func (kenshinLayout) Filter(rec []string) bool {
+	return true
+}
 
-			// 12.OP　０１
-			cRec[12] = ""
+// Check は健診データの出力行を検査する（必須項目・検査値の範囲・BMI）
+func (l kenshinLayout) Check(rec []string, cRec []string) {
+	checkRequired(rec, cRec)
+	checkRanges(l.Name(), rec, columnNames(l.Columns()), cRec)
+	checkBMI(l.Name(), rec, cRec)
+}
 
-			// 13.OP　０２
-			cRec[13] = ""
+func (kenshinLayout) Map(rec []string) []string {
+	cRec := make([]string, 98) //出力する項目数
 
-			// 14.OP　０３
-			cRec[14] = ""
+	// 0.実施健診機関CD
+	cRec[0] = "415201"
 
-			// 15.OP　０４
-			cRec[15] = ""
+	// 1.健診種別CD
+	if kazokuCheck(rec[3]) {
+		cRec[1] = "2000" // 家族
+	} else {
+		cRec[1] = "1000" // 本人
+	}
 
-			// 16.OP　０５
-			cRec[16] = ""
+	// 2.受診日
+	cRec[2] = strings.Replace((rec[4]), "-", "/", -1)
 
-			// 17.OP　０６
-			cRec[17] = ""
+	// 3.事業所記号
+	cRec[3] = rec[5]
 
-			// 18.OP　０７
-			cRec[18] = ""
+	// 4.証番号
+	cRec[4] = rec[6]
 
-			// 19.OP　０８
-			cRec[19] = ""
+	// 5.資格区分
+	if kazokuCheck(rec[3]) {
+		cRec[5] = "1" // 家族
+	} else {
+		cRec[5] = "0" // 本人
+	}
 
-			// 20.OP　０９
-			cRec[20] = ""
+	// 6.続柄
+	cRec[6] = ""
 
-			// 21.OP　１０
-			cRec[21] = ""
+	// 7.枝番
+	cRec[7] = ""
 
-			// 22.OP 11
-			cRec[22] = ""
+	// 8.漢字氏名
+	cRec[8] = ""
 
-			// 23.請求区分
-			cRec[23] = "0"
+	// 9.カナ氏名
+	cRec[9] = string(norm.NFKC.Bytes([]byte(rec[7])))
 
-			// 24.健診金額
-			cRec[24] = "7300"
+	// 10.性別
+	cRec[10] = sei(rec[8])
 
-			// 25.法定金額
-			cRec[25] = ""
+	// 11.生年月日
+	cRec[11] = WaToSeireki(rec[9])
 
-			// 26.請求金額
-			cRec[26] = "7300"
+	// 12.OP　０１
+	cRec[12] = ""
 
-			// 27.支払先CD
-			cRec[27] = "415201"
+	// 13.OP　０２
+	cRec[13] = ""
 
-			// 28.身長
-			cRec[28] = inRecs[J][11]
+	// 14.OP　０３
+	cRec[14] = ""
 
-			// 29.体重
-			cRec[29] = inRecs[J][12]
+	// 15.OP　０４
+	cRec[15] = ""
 
-			// 30.BMI
-			cRec[30] = inRecs[J][13]
+	// 16.OP　０５
+	cRec[16] = ""
 
-			// 31.腹囲
-			cRec[31] = inRecs[J][14]
+	// 17.OP　０６
+	cRec[17] = ""
 
-			// 32.身体検査判定
-			cRec[32] = tokkijiko(inRecs[J][44])
+	// 18.OP　０７
+	cRec[18] = ""
 
-			// 33.血圧（収縮期）
-			// 34.血圧（拡張期）
-			if inRecs[J][15] == "" {
-				cRec[33] = ""
-				cRec[34] = ""
-			} else if inRecs[J][17] == "" {
-				cRec[33] = inRecs[J][15]
-				cRec[34] = inRecs[J][16]
-			} else {
-				k1H, _ := strconv.Atoi(inRecs[J][15])
-				k1L, _ := strconv.Atoi(inRecs[J][16])
-				k2H, _ := strconv.Atoi(inRecs[J][17])
-				k2L, _ := strconv.Atoi(inRecs[J][18])
-				kH := (k1H + k2H) / 2
-				kL := (k1L + k2L) / 2
-				cRec[33] = fmt.Sprint(kH)
-				cRec[34] = fmt.Sprint(kL)
-			}
+	// 19.OP　０８
+	cRec[19] = ""
 
-			// 35.空腹時中性脂肪
-			if inRecs[J][179] == "" {
-				cRec[35] = inRecs[J][19]
-			} else {
-				cRec[35] = ""
-			}
+	// 20.OP　０９
+	cRec[20] = ""
 
-			// 36.随時中性脂肪
-			cRec[36] = inRecs[J][179]
+	// 21.OP　１０
+	cRec[21] = ""
 
-			// 37.HDL・CO
-			cRec[37] = inRecs[J][20]
+	// 22.OP 11
+	cRec[22] = ""
 
-			// 38.LDL・CO
-			cRec[38] = inRecs[J][21]
+	// 23.請求区分
+	cRec[23] = "0"
 
-			// 39.Non・HDLCO
-			cRec[39] = ""
+	// 24.健診金額
+	cRec[24] = "7300"
 
-			// 40.AST(GOT)
-			cRec[40] = inRecs[J][22]
+	// 25.法定金額
+	cRec[25] = ""
 
-			// 41.ALT(GPT)
-			cRec[41] = inRecs[J][23]
+	// 26.請求金額
+	cRec[26] = "7300"
 
-			// 42.γ・GTP
-			cRec[42] = inRecs[J][24]
+	// 27.支払先CD
+	cRec[27] = "415201"
 
-			// 43.空腹時血糖
-			cRec[43] = inRecs[J][25]
+	// 28.身長
+	cRec[28] = rec[11]
 
-			// 44.HｂA1ｃ
-			cRec[44] = inRecs[J][26]
+	// 29.体重
+	cRec[29] = rec[12]
 
-			// 45.随時血糖
-			cRec[45] = inRecs[J][25]
+	// 30.BMI
+	cRec[30] = rec[13]
 
-			// 空腹時血糖・随時血糖の処理
-			Eattime, _ := strconv.ParseFloat(inRecs[J][28], 32)
-			if (inRecs[J][27] == "とった") && (Eattime < 10) {
-				cRec[43] = "" // 随時血糖なので、空腹時血糖の値を空欄にする
-			} else {
-				cRec[45] = "" // 空腹時血糖なので、随時血糖の値を空欄にする
-			}
+	// 31.腹囲
+	cRec[31] = rec[14]
 
-			// 46.採血時間
-			cRec[46] = ""
+	// 32.身体検査判定
+	cRec[32] = tokkijiko(rec[44])
 
-			// 47.尿糖
-			cRec[47] = nyo(inRecs[J][29])
+	// 33.血圧（収縮期）
+	// 34.血圧（拡張期）
+	if rec[15] == "" {
+		cRec[33] = ""
+		cRec[34] = ""
+	} else if rec[17] == "" {
+		cRec[33] = rec[15]
+		cRec[34] = rec[16]
+	} else {
+		k1H, _ := strconv.Atoi(rec[15])
+		k1L, _ := strconv.Atoi(rec[16])
+		k2H, _ := strconv.Atoi(rec[17])
+		k2L, _ := strconv.Atoi(rec[18])
+		kH := (k1H + k2H) / 2
+		kL := (k1L + k2L) / 2
+		cRec[33] = fmt.Sprint(kH)
+		cRec[34] = fmt.Sprint(kL)
+	}
 
-			// 48.尿蛋白
-			cRec[48] = nyo(inRecs[J][30])
+	// 35.空腹時中性脂肪
+	if rec[179] == "" {
+		cRec[35] = rec[19]
+	} else {
+		cRec[35] = ""
+	}
 
-			// 49.未実施の場合その理由
-			cRec[49] = nyoNotReason(inRecs[J][180])
+	// 36.随時中性脂肪
+	cRec[36] = rec[179]
 
-			// 50.白血球数
-			cRec[50] = inRecs[J][31]
+	// 37.HDL・CO
+	cRec[37] = rec[20]
 
-			// 51.赤血球数
-			cRec[51] = inRecs[J][32]
+	// 38.LDL・CO
+	cRec[38] = rec[21]
 
-			// 52.血色素量
-			cRec[52] = inRecs[J][33]
+	// 39.Non・HDLCO
+	cRec[39] = ""
 
-			// 53.ヘマトクリット
-			cRec[53] = inRecs[J][34]
+	// 40.AST(GOT)
+	cRec[40] = rec[22]
 
-			// 54.心電図所見
-			cRec[54] = syokenumu(inRecs[J][76])
+	// 41.ALT(GPT)
+	cRec[41] = rec[23]
 
-			// 55.眼底精密所見
-			cRec[55] = syokenumu(inRecs[J][84])
+	// 42.γ・GTP
+	cRec[42] = rec[24]
 
-			// 56.血清クレアチニン
-			cRec[56] = inRecs[J][35]
+	// 43.空腹時血糖
+	cRec[43] = rec[25]
 
-			// 57.eGFR
-			cRec[57] = inRecs[J][36]
+	// 44.HｂA1ｃ
+	cRec[44] = rec[26]
 
-			// 58.HBｓ抗原
-			cRec[58] = nyo(inRecs[J][37])
+	// 45.随時血糖
+	cRec[45] = rec[25]
 
-			// 59.HBs抗体
-			cRec[59] = nyo(inRecs[J][38])
+	// 空腹時血糖・随時血糖の処理
+	Eattime, _ := strconv.ParseFloat(rec[28], 32)
+	if (rec[27] == "とった") && (Eattime < 10) {
+		cRec[43] = "" // 随時血糖なので、空腹時血糖の値を空欄にする
+	} else {
+		cRec[45] = "" // 空腹時血糖なので、随時血糖の値を空欄にする
+	}
 
-			// 60.HCV抗体価精密測定
-			cRec[60] = nyo(inRecs[J][39])
+	// 46.採血時間
+	cRec[46] = ""
 
-			// 61.胸部X線検査判定
-			cRec[61] = syokenumu(inRecs[J][74])
+	// 47.尿糖
+	cRec[47] = nyo(rec[29])
 
-			// 62.尿酸値
-			cRec[62] = inRecs[J][40]
+	// 48.尿蛋白
+	cRec[48] = nyo(rec[30])
 
-			// 63.腹部超音波検査判定
-			cRec[63] = syokenumu(inRecs[J][86])
+	// 49.未実施の場合その理由
+	cRec[49] = nyoNotReason(rec[180])
 
-			// 64.便潜血
-			if inRecs[J][42] == "＋" {
-				cRec[64] = nyo(inRecs[J][42])
-			} else {
-				cRec[64] = nyo(inRecs[J][41])
-			}
+	// 50.白血球数
+	cRec[50] = rec[31]
 
-			// 65.総合判定
-			//cRec[65] = inRecs[J][43]
-
-			// 66.メタボリック判定
-			cRec[66] = ""
-
-			// 67.医師の診断
-			// 65.総合判定
-			sogo := ""
-			var h [7][2]string
-			h[0][0] = inRecs[J][44] //身体計測判定
-			h[0][1] = inRecs[J][45] //身体計測所見
-			h[1][0] = inRecs[J][50] //血圧判定
-			h[1][1] = inRecs[J][51] //血圧所見
-			if inRecs[J][52] != "" && inRecs[J][53] == "" {
-				h[2][0] = inRecs[J][52] //尿蛋白判定
-				h[2][1] = inRecs[J][67] //腎機能所見
-			} else {
-				h[2][0] = inRecs[J][52] //尿蛋白判定
-				h[2][1] = inRecs[J][53] //尿蛋白所見
-			}
-			h[3][0] = inRecs[J][54] //尿糖判定
-			h[3][1] = inRecs[J][55] //尿糖所見
-			h[4][0] = inRecs[J][60] //血中脂質判定
-			h[4][1] = inRecs[J][61] //血中脂質所見
-			h[5][0] = inRecs[J][62] //肝機能判定
-			h[5][1] = inRecs[J][63] //肝機能所見
-			h[6][0] = inRecs[J][64] //糖代謝判定
-			h[6][1] = inRecs[J][65] //糖代謝所見
-
-			hKigo := [...]string{"Ｆ", "Ｅ", "Ｄ", "Ｇ", "Ｃ"}
-			for k := 0; k < 5; k++ {
-				for l := 0; l < 7; l++ {
-					if h[l][0] == hKigo[k] {
-						if h[l][1] != "" {
-							if sogo == "" {
-								sogo = h[l][1]
-							} else {
-								sogo = sogo + "　" + h[l][1]
-							}
-						}
-					}
-				}
-			}
+	// 51.赤血球数
+	cRec[51] = rec[32]
 
-			cRec[67] = sogo
+	// 52.血色素量
+	cRec[52] = rec[33]
 
-			sogoHantei := 0
-			for l := 0; l < 7; l++ {
-				if rank(h[l][0]) > sogoHantei {
-					sogoHantei = rank(h[l][0])
-				}
-			}
-			cRec[65] = rankS(sogoHantei)
-
-			// 68.医師名
-			cRec[68] = inRecs[J][100]
-
-			// 69.既往歴
-			// 70.具体的な既往歴
-			kiou := ""
-			for k := 0; k < 10; k++ {
-				kp := 101 + (k * 3)
-				kiouB := kiouSet(inRecs[J][kp])
-				kiouN := inRecs[J][kp+1]
-				kiouT := inRecs[J][kp+2]
-
-				if kiouB != "" {
-					if kiou == "" {
-						kiou = kiouB
-					} else {
-						kiou = kiou + " " + kiouB
-					}
+	// 53.ヘマトクリット
+	cRec[53] = rec[34]
 
-					if kiouN != "" {
-						kiou = kiou + " " + kiouN + "才"
-					}
+	// 54.心電図所見
+	cRec[54] = syokenumu(rec[76])
 
-					if kiouT != "" {
-						kiou = kiou + " " + kiouT
-					}
-				}
-			}
+	// 55.眼底精密所見
+	cRec[55] = syokenumu(rec[84])
 
-			cRec[70] = kiou
+	// 56.血清クレアチニン
+	cRec[56] = rec[35]
 
-			if kiou != "" {
-				cRec[69] = "1" // あり
-			} else {
-				cRec[69] = "2" // なし
-			}
+	// 57.eGFR
+	cRec[57] = rec[36]
 
-			// 71.自覚症状
-			// 72.自覚症状所見
-			jikaku := ""
-			for k := 0; k < 5; k++ {
-				kp := 131 + k
-				jikakuS := inRecs[J][kp]
+	// 58.HBｓ抗原
+	cRec[58] = nyo(rec[37])
 
-				if jikakuS != "" {
-					if jikaku == "" {
-						jikaku = jikakuS
-					} else {
-						jikaku = jikaku + " " + jikakuS
-					}
+	// 59.HBs抗体
+	cRec[59] = nyo(rec[38])
 
-				}
-			}
+	// 60.HCV抗体価精密測定
+	cRec[60] = nyo(rec[39])
 
-			if jikaku == "特になし" {
-				jikaku = ""
-			}
+	// 61.胸部X線検査判定
+	cRec[61] = syokenumu(rec[74])
 
-			cRec[72] = jikaku
+	// 62.尿酸値
+	cRec[62] = rec[40]
 
-			if jikaku != "" {
-				cRec[71] = "1" // あり
-			} else {
-				cRec[71] = "2" // なし
-			}
+	// 63.腹部超音波検査判定
+	cRec[63] = syokenumu(rec[86])
 
-			// 73.他覚症状
-			// 74.他覚症状所見
-			takaku := ""
-			for k := 0; k < 3; k++ {
-				kp := 136 + k
-				takakuS := inRecs[J][kp]
+	// 64.便潜血
+	if rec[42] == "＋" {
+		cRec[64] = nyo(rec[42])
+	} else {
+		cRec[64] = nyo(rec[41])
+	}
 
-				if takakuS != "" {
-					if takaku == "" {
-						takaku = takakuS
+	// 65.総合判定
+	//cRec[65] = rec[43]
+
+	// 66.メタボリック判定
+	cRec[66] = ""
+
+	// 67.医師の診断
+	// 65.総合判定
+	sogo := ""
+	var h [7][2]string
+	h[0][0] = rec[44] //身体計測判定
+	h[0][1] = rec[45] //身体計測所見
+	h[1][0] = rec[50] //血圧判定
+	h[1][1] = rec[51] //血圧所見
+	if rec[52] != "" && rec[53] == "" {
+		h[2][0] = rec[52] //尿蛋白判定
+		h[2][1] = rec[67] //腎機能所見
+	} else {
+		h[2][0] = rec[52] //尿蛋白判定
+		h[2][1] = rec[53] //尿蛋白所見
+	}
+	h[3][0] = rec[54] //尿糖判定
+	h[3][1] = rec[55] //尿糖所見
+	h[4][0] = rec[60] //血中脂質判定
+	h[4][1] = rec[61] //血中脂質所見
+	h[5][0] = rec[62] //肝機能判定
+	h[5][1] = rec[63] //肝機能所見
+	h[6][0] = rec[64] //糖代謝判定
+	h[6][1] = rec[65] //糖代謝所見
+
+	hKigo := [...]string{"Ｆ", "Ｅ", "Ｄ", "Ｇ", "Ｃ"}
+	for k := 0; k < 5; k++ {
+		for l := 0; l < 7; l++ {
+			if h[l][0] == hKigo[k] {
+				if h[l][1] != "" {
+					if sogo == "" {
+						sogo = h[l][1]
 					} else {
-						takaku = takaku + " " + takakuS
+						sogo = sogo + "　" + h[l][1]
 					}
-
 				}
 			}
-
-			if takaku == "異常なし" {
-				takaku = ""
-			}
-
-			cRec[74] = takaku
-
-			if takaku != "" {
-				cRec[73] = "1" // あり
-			} else {
-				cRec[73] = "2" // なし
-			}
-
-			// 75.保健指導レベル
-			cRec[75] = ""
-
-			// 76.服薬・血圧
-			cRec[76] = yesNo(inRecs[J][139])
-
-			// 77.服薬・血糖
-			cRec[77] = yesNo(inRecs[J][140])
-
-			// 78.服薬・コレステロール
-			cRec[78] = yesNo(inRecs[J][141])
-
-			// 79.脳卒中
-			cRec[79] = yesNo(inRecs[J][142])
-
-			// 80.心臓病
-			cRec[80] = yesNo(inRecs[J][143])
-
-			// 81.慢性腎臓病
-			cRec[81] = yesNo(inRecs[J][144])
-
-			// 82.貧血
-			cRec[82] = yesNo(inRecs[J][145])
-
-			// 83.たばこ
-			cRec[83] = tabako(inRecs[J][146])
-
-			// 84.体重１０㌔増
-			cRec[84] = yesNo(inRecs[J][147])
-
-			// 85.汗かく運動
-			cRec[85] = yesNo(inRecs[J][148])
-
-			// 86.歩行１時間以上
-			cRec[86] = yesNo(inRecs[J][149])
-
-			// 87.歩く速度
-			cRec[87] = yesNo(inRecs[J][150])
-
-			// 88.食事噛む状態
-			cRec[88] = eat2(inRecs[J][151])
-
-			// 89.食べる速度
-			cRec[89] = eat(inRecs[J][152])
-
-			// 90.就寝前食事
-			cRec[90] = yesNo(inRecs[J][153])
-
-			// 91.間食
-			cRec[91] = drink(inRecs[J][154])
-
-			// 92.朝食抜き
-			cRec[92] = yesNo(inRecs[J][155])
-
-			// 93.お酒・頻度
-			cRec[93] = sake(inRecs[J][156])
-
-			// 94.お酒・量
-			cRec[94] = sakeryo(inRecs[J][157])
-
-			// 95.睡眠
-			cRec[95] = yesNo(inRecs[J][158])
-
-			// 96.改善の意思
-			cRec[96] = seikatsu(inRecs[J][159])
-
-			// 97.指導受診歴
-			cRec[97] = yesNo(inRecs[J][160])
-
-			//writer.Write(cRec)
-			row = sheet.AddRow()
-			for _, cell = range cRec {
-				// sheet.Cell(r, c).Value = cell
-				vcell = row.AddCell()
-				vcell.Value = cell
-			}
 		}
 	}
 
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
-}
-
-func gastricConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
-
-	recLen := 11 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
-
-	day := time.Now()
-
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員胃がん検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
-
-	//タイトル行
-	cRec[0] = "支払先CD"
-	cRec[1] = "受診日"
-	cRec[2] = "事業所記号"
-	cRec[3] = "証番号"
-	cRec[4] = "資格区分"
-	cRec[5] = "カナ氏名"
-	cRec[6] = "性別"
-	cRec[7] = "生年月日"
-	cRec[8] = "結果"
-	cRec[9] = "所見"
-	cRec[10] = "検査区分"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
-	}
+	cRec[67] = findings("健診", rec, "医師の診断", sogo)
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
+	sogoHantei := 0
+	for l := 0; l < 7; l++ {
+		if rank(h[l][0]) > sogoHantei {
+			sogoHantei = rank(h[l][0])
 		}
+	}
+	cRec[65] = rankS(sogoHantei)
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][78] != "" || inRecs[J][80] != "" {
-				// 0.支払先CD
-				cRec[0] = "415201"
-
-				// 1.受診日
-				cRec[1] = strings.Replace((inRecs[J][4]), "-", "/", -1)
-
-				// 2.事業所記号
-				cRec[2] = inRecs[J][5]
-
-				// 3.証番号
-				cRec[3] = inRecs[J][6]
-
-				// 4.資格区分
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[4] = "1" // 家族
-				} else {
-					cRec[4] = "0" // 本人
-				}
-
-				// 5.カナ氏名
-				cRec[5] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
-
-				// 6.性別
-				cRec[6] = sei(inRecs[J][8])
-
-				// 7.生年月日
-				cRec[7] = WaToSeireki(inRecs[J][9])
-
-				// 8.結果区分
-				cRec[8] = kekka(inRecs[J][78])
-
-				// 9.所見
-
-				//胃部X線か胃カメラか
-				syoken := ""
-				if inRecs[J][78] != "" && inRecs[J][78] != "Ａ" && inRecs[J][78] != "Ｂ" {
-					// 胃部X線の場合
-					for k := 0; k < 3; k++ {
-						kp := 161 + k
-						syokenS := inRecs[J][kp]
+	// 68.医師名
+	cRec[68] = rec[100]
 
-						if syokenS != "" {
-							if syoken == "" {
-								syoken = syokenS
-							} else {
-								syoken = syoken + " " + syokenS
-							}
+	// 69.既往歴
+	// 70.具体的な既往歴
+	kious := parseKiou(rec)
+	checkKiou(rec, kious)
+	kiou := kiouText(kious)
 
-						}
-					}
-				} else if inRecs[J][80] != "" {
-					// 胃カメラの場合
-					syoken := ""
-					for k := 0; k < 3; k++ {
-						kp := 164 + k
-						syokenS := inRecs[J][kp]
-
-						if syokenS != "" {
-							if syoken == "" {
-								syoken = syokenS
-							} else {
-								syoken = syoken + " " + syokenS
-							}
-
-						}
-					}
-				}
+	cRec[70] = freeText("健診", rec, "具体的な既往歴", kiou)
 
-				cRec[9] = syoken
+	if kiou != "" {
+		cRec[69] = "1" // あり
+	} else {
+		cRec[69] = "2" // なし
+	}
 
-				// 10.結果
-				cRec[10] = "レントゲン"
+	// 71.自覚症状
+	// 72.自覚症状所見
+	jikaku := ""
+	for k := 0; k < 5; k++ {
+		kp := 131 + k
+		jikakuS := rec[kp]
 
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
-				}
+		if jikakuS != "" {
+			if jikaku == "" {
+				jikaku = jikakuS
+			} else {
+				jikaku = jikaku + " " + jikakuS
 			}
+
 		}
 	}
 
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
-}
-
-func uterineConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
-
-	recLen := 11 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
-
-	day := time.Now()
-
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員子宮がん検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
-
-	//タイトル行
-	cRec[0] = "支払先CD"
-	cRec[1] = "受診日"
-	cRec[2] = "事業所記号"
-	cRec[3] = "証番号"
-	cRec[4] = "資格区分"
-	cRec[5] = "カナ氏名"
-	cRec[6] = "性別"
-	cRec[7] = "生年月日"
-	cRec[8] = "結果"
-	cRec[9] = "所見"
-	cRec[10] = "検査区分"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
+	if jikaku == "特になし" {
+		jikaku = ""
 	}
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
-		}
-
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][90] != "" {
-				// 0.支払先CD
-				cRec[0] = "415201"
-
-				// 1.受診日
-				cRec[1] = strings.Replace((inRecs[J][4]), "-", "/", -1)
-
-				// 2.事業所記号
-				cRec[2] = inRecs[J][5]
-
-				// 3.証番号
-				cRec[3] = inRecs[J][6]
+	cRec[72] = findings("健診", rec, "自覚症状所見", jikaku)
 
-				// 4.資格区分
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[4] = "1" // 家族
-				} else {
-					cRec[4] = "0" // 本人
-				}
+	if jikaku != "" {
+		cRec[71] = "1" // あり
+	} else {
+		cRec[71] = "2" // なし
+	}
 
-				// 5.カナ氏名
-				cRec[5] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
+	// 73.他覚症状
+	// 74.他覚症状所見
+	takaku := ""
+	for k := 0; k < 3; k++ {
+		kp := 136 + k
+		takakuS := rec[kp]
 
-				// 6.性別
-				cRec[6] = sei(inRecs[J][8])
+		if takakuS != "" {
+			if takaku == "" {
+				takaku = takakuS
+			} else {
+				takaku = takaku + " " + takakuS
+			}
 
-				// 7.生年月日
-				cRec[7] = WaToSeireki(inRecs[J][9])
+		}
+	}
 
-				// 8.結果
-				cRec[8] = kekka(inRecs[J][90])
+	if takaku == "異常なし" {
+		takaku = ""
+	}
 
-				// 9.所見
+	cRec[74] = findings("健診", rec, "他覚症状所見", takaku)
 
-				cRec[9] = ""
+	if takaku != "" {
+		cRec[73] = "1" // あり
+	} else {
+		cRec[73] = "2" // なし
+	}
 
-				// 10.検査区分
-				cRec[10] = ""
+	// 75.保健指導レベル
+	cRec[75] = ""
 
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
-				}
-			}
-		}
-	}
+	// 76.服薬・血圧
+	cRec[76] = yesNo(rec[139])
 
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
-}
+	// 77.服薬・血糖
+	cRec[77] = yesNo(rec[140])
 
-func breastConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
+	// 78.服薬・コレステロール
+	cRec[78] = yesNo(rec[141])
 
-	recLen := 11 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
+	// 79.脳卒中
+	cRec[79] = yesNo(rec[142])
 
-	day := time.Now()
+	// 80.心臓病
+	cRec[80] = yesNo(rec[143])
 
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員乳がん検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
+	// 81.慢性腎臓病
+	cRec[81] = yesNo(rec[144])
 
-	//タイトル行
-	cRec[0] = "支払先CD"
-	cRec[1] = "受診日"
-	cRec[2] = "事業所記号"
-	cRec[3] = "証番号"
-	cRec[4] = "資格区分"
-	cRec[5] = "カナ氏名"
-	cRec[6] = "性別"
-	cRec[7] = "生年月日"
-	cRec[8] = "結果"
-	cRec[9] = "所見"
-	cRec[10] = "検査区分"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
-	}
+	// 82.貧血
+	cRec[82] = yesNo(rec[145])
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
-		}
+	// 83.たばこ
+	cRec[83] = tabako(rec[146])
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][94] != "" {
-				// 0.支払先CD
-				cRec[0] = "415201"
+	// 84.体重１０㌔増
+	cRec[84] = yesNo(rec[147])
 
-				// 1.受診日
-				cRec[1] = strings.Replace((inRecs[J][4]), "-", "/", -1)
+	// 85.汗かく運動
+	cRec[85] = yesNo(rec[148])
 
-				// 2.事業所記号
-				cRec[2] = inRecs[J][5]
+	// 86.歩行１時間以上
+	cRec[86] = yesNo(rec[149])
 
-				// 3.証番号
-				cRec[3] = inRecs[J][6]
+	// 87.歩く速度
+	cRec[87] = yesNo(rec[150])
 
-				// 4.資格区分
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[4] = "1" // 家族
-				} else {
-					cRec[4] = "0" // 本人
-				}
+	// 88.食事噛む状態
+	cRec[88] = eat2(rec[151])
 
-				// 5.カナ氏名
-				cRec[5] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
+	// 89.食べる速度
+	cRec[89] = eat(rec[152])
 
-				// 6.性別
-				cRec[6] = sei(inRecs[J][8])
+	// 90.就寝前食事
+	cRec[90] = yesNo(rec[153])
 
-				// 7.生年月日
-				cRec[7] = WaToSeireki(inRecs[J][9])
+	// 91.間食
+	cRec[91] = drink(rec[154])
 
-				// 8.結果
-				cRec[8] = kekka(inRecs[J][94])
+	// 92.朝食抜き
+	cRec[92] = yesNo(rec[155])
 
-				// 9.所見
+	// 93.お酒・頻度
+	cRec[93] = sake(rec[156])
 
-				syoken := ""
-				if inRecs[J][94] != "" && inRecs[J][94] != "Ａ" && inRecs[J][94] != "Ｂ" {
-					for k := 0; k < 3; k++ {
-						kp := 171 + k
-						syokenS := inRecs[J][kp]
+	// 94.お酒・量
+	cRec[94] = sakeryo(rec[157])
 
-						if syokenS != "" {
-							if syoken == "" {
-								syoken = syokenS
-							} else {
-								syoken = syoken + " " + syokenS
-							}
+	// 95.睡眠
+	cRec[95] = yesNo(rec[158])
 
-						}
-					}
-				}
+	// 96.改善の意思
+	cRec[96] = seikatsu(rec[159])
 
-				cRec[9] = syoken
+	// 97.指導受診歴
+	cRec[97] = yesNo(rec[160])
 
-				// 10.検査区分
-				cRec[10] = "超音波"
+	return cRec
+}
 
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
-				}
+// 胃がん検診
+var gastricLayout = cancerLayout{
+	name:   "胃がん検診",
+	key:    "gastric",
+	source: "78列 胃X線判定・80列 胃カメラ判定・161～166列 所見",
+	filter: func(rec []string) bool { return rec[78] != "" || rec[80] != "" },
+	result: func(rec []string) string {
+		if gastroscopy(rec) {
+			return kekka(rec[80])
+		}
+		return kekka(rec[78])
+	},
+	syoken: func(rec []string) string {
+		//胃部X線か胃カメラか
+		if gastroscopy(rec) {
+			if ijo(rec[80]) {
+				return joinCols(rec, 164, 3) // 胃カメラの場合
 			}
+		} else if ijo(rec[78]) {
+			return joinCols(rec, 161, 3) // 胃部X線の場合
 		}
-	}
-
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
+		return ""
+	},
+	kensa: func(rec []string) string {
+		if gastroscopy(rec) {
+			return "内視鏡"
+		}
+		return "レントゲン"
+	},
 }
 
-func prostateConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
-
-	recLen := 11 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
-
-	day := time.Now()
-
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員前立腺がん検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
+// gastroscopy は胃カメラだけを受けた行か（胃部X線の判定が空欄）
+func gastroscopy(rec []string) bool {
+	return rec[78] == "" && rec[80] != ""
+}
 
-	//タイトル行
-	cRec[0] = "支払先CD"
-	cRec[1] = "受診日"
-	cRec[2] = "事業所記号"
-	cRec[3] = "証番号"
-	cRec[4] = "資格区分"
-	cRec[5] = "カナ氏名"
-	cRec[6] = "性別"
-	cRec[7] = "生年月日"
-	cRec[8] = "結果"
-	cRec[9] = "所見"
-	cRec[10] = "検査区分"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
-	}
+// 子宮がん検診
+var uterineLayout = cancerLayout{
+	name:   "子宮がん検診",
+	key:    "uterine",
+	source: "90列 子宮判定",
+	filter: func(rec []string) bool { return rec[90] != "" },
+	result: func(rec []string) string { return kekka(rec[90]) },
+}
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
+// 乳がん検診（超音波）
+var breastLayout = cancerLayout{
+	name:   "乳がん検診",
+	key:    "breast",
+	source: "94列 乳腺超音波判定・171～173列 所見",
+	filter: func(rec []string) bool { return rec[94] != "" },
+	result: func(rec []string) string { return kekka(rec[94]) },
+	syoken: func(rec []string) string {
+		if ijo(rec[94]) {
+			return joinCols(rec, 171, 3)
 		}
+		return ""
+	},
+	kensa: kubun("超音波"),
+}
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][175] != "" {
-				// 0.支払先CD
-				cRec[0] = "415201"
-
-				// 1.受診日
-				cRec[1] = strings.Replace((inRecs[J][4]), "-", "/", -1)
-
-				// 2.事業所記号
-				cRec[2] = inRecs[J][5]
-
-				// 3.証番号
-				cRec[3] = inRecs[J][6]
-
-				// 4.資格区分
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[4] = "1" // 家族
-				} else {
-					cRec[4] = "0" // 本人
-				}
-
-				// 5.カナ氏名
-				cRec[5] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
-
-				// 6.性別
-				cRec[6] = sei(inRecs[J][8])
-
-				// 7.生年月日
-				cRec[7] = WaToSeireki(inRecs[J][9])
-
-				// 8.結果
-				cRec[8] = kekka(inRecs[J][175])
-
-				// 9.所見
-
-				cRec[9] = "PSA " + inRecs[J][174]
+// 前立腺がん検診
+var prostateLayout = cancerLayout{
+	name:   "前立腺がん検診",
+	key:    "prostate",
+	source: "175列 前立腺判定・174列 PSA",
+	filter: func(rec []string) bool { return rec[175] != "" },
+	result: func(rec []string) string { return kekka(rec[175]) },
+	syoken: func(rec []string) string { return "PSA " + rec[174] },
+}
 
-				// 10.検査区分
-				cRec[10] = ""
+// 乳がん検診（マンモグラフィー）
+var mmgLayout = cancerLayout{
+	name:   "マンモ検診",
+	key:    "mmg",
+	source: "96列 マンモ判定・176～178列 所見",
+	filter: func(rec []string) bool { return rec[96] != "" },
+	result: func(rec []string) string { return kekka(rec[96]) },
+	syoken: func(rec []string) string {
+		if ijo(rec[96]) {
+			return joinCols(rec, 176, 3)
+		}
+		return ""
+	},
+	kensa: kubun("マンモ"),
+}
 
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
-				}
+// 乳がん検診（超音波とマンモを１人１件にまとめる）
+// 結果は悪い方、所見は両方をつなぐ
+var breastCombinedLayout = cancerLayout{
+	name:   "乳がん検診",
+	key:    "breast",
+	source: "94列 乳腺超音波判定・96列 マンモ判定・171～173・176～178列 所見",
+	filter: func(rec []string) bool { return rec[94] != "" || rec[96] != "" },
+	parts:  []layoutPart{{mmgLayout, []int{96, 176, 177, 178}}},
+	result: func(rec []string) string {
+		h := rec[94]
+		if rank(rec[96]) > rank(h) {
+			h = rec[96]
+		}
+		return kekka(h)
+	},
+	syoken: func(rec []string) string {
+		syoken := breastLayout.syoken(rec)
+		if s := mmgLayout.syoken(rec); s != "" {
+			if syoken == "" {
+				syoken = s
+			} else {
+				syoken = syoken + " " + s
 			}
 		}
-	}
-
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
+		return syoken
+	},
+	kensa: func(rec []string) string {
+		switch {
+		case rec[94] != "" && rec[96] != "":
+			return "超音波+マンモ"
+		case rec[96] != "":
+			return "マンモ"
+		default:
+			return "超音波"
+		}
+	},
 }
 
-func mmgConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
-
-	recLen := 11 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
-
-	day := time.Now()
-
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員マンモ検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
-
-	//タイトル行
-	cRec[0] = "支払先CD"
-	cRec[1] = "受診日"
-	cRec[2] = "事業所記号"
-	cRec[3] = "証番号"
-	cRec[4] = "資格区分"
-	cRec[5] = "カナ氏名"
-	cRec[6] = "性別"
-	cRec[7] = "生年月日"
-	cRec[8] = "結果"
-	cRec[9] = "所見"
-	cRec[10] = "検査区分"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
-	}
-
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
+// 大腸がん検診（便潜血２日法）
+var colorectalLayout = cancerLayout{
+	name:   "大腸がん検診",
+	key:    "colorectal",
+	source: "41・42列 便潜血（1日目・2日目）",
+	filter: func(rec []string) bool { return rec[41] != "" || rec[42] != "" },
+	result: bensenketsu,
+	syoken: func(rec []string) string {
+		if bensenketsu(rec) == "4" {
+			return "便潜血 1日目" + nyo(rec[41]) + " 2日目" + nyo(rec[42])
 		}
+		return ""
+	},
+	kensa: func(rec []string) string {
+		if rec[41] != "" && rec[42] != "" {
+			return "便潜血2日法"
+		}
+		return "便潜血1日法"
+	},
+}
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][96] != "" {
-				// 0.支払先CD
-				cRec[0] = "415201"
-
-				// 1.受診日
-				cRec[1] = strings.Replace((inRecs[J][4]), "-", "/", -1)
-
-				// 2.事業所記号
-				cRec[2] = inRecs[J][5]
-
-				// 3.証番号
-				cRec[3] = inRecs[J][6]
-
-				// 4.資格区分
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[4] = "1" // 家族
+// 肺がん検診（胸部X線・喀痰）
+var lungLayout = cancerLayout{
+	name:   "肺がん検診",
+	key:    "lung",
+	source: "74列 胸部X線判定（所見・喀痰の列は設定 lung）",
+	filter: func(rec []string) bool { return rec[74] != "" },
+	result: func(rec []string) string {
+		h := rec[74]
+		if k := colValue(rec, conf.Lung.Kakutan); rank(k) > rank(h) {
+			h = k
+		}
+		return kekka(h)
+	},
+	syoken: func(rec []string) string {
+		if !ijo(rec[74]) {
+			return ""
+		}
+		syoken := ""
+		for _, c := range conf.Lung.Syoken {
+			s := colValue(rec, c)
+			if s != "" {
+				if syoken == "" {
+					syoken = s
 				} else {
-					cRec[4] = "0" // 本人
-				}
-
-				// 5.カナ氏名
-				cRec[5] = string(norm.NFKC.Bytes([]byte(inRecs[J][7])))
-
-				// 6.性別
-				cRec[6] = sei(inRecs[J][8])
-
-				// 7.生年月日
-				cRec[7] = WaToSeireki(inRecs[J][9])
-
-				// 8.結果
-				cRec[8] = kekka(inRecs[J][96])
-
-				// 9.所見
-
-				syoken := ""
-				if inRecs[J][96] != "" && inRecs[J][96] != "Ａ" && inRecs[J][96] != "Ｂ" {
-					for k := 0; k < 3; k++ {
-						kp := 176 + k
-						syokenS := inRecs[J][kp]
-
-						if syokenS != "" {
-							if syoken == "" {
-								syoken = syokenS
-							} else {
-								syoken = syoken + " " + syokenS
-							}
-
-						}
-					}
-				}
-
-				cRec[9] = syoken
-
-				// 10.検査区分
-				cRec[10] = "マンモ"
-
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
+					syoken = syoken + " " + s
 				}
 			}
 		}
-	}
-
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
+		return syoken
+	},
+	kensa: func(rec []string) string {
+		if colValue(rec, conf.Lung.Kakutan) != "" {
+			return "胸部X線+喀痰"
+		}
+		return "胸部X線"
+	},
 }
 
-func dexaConversion(filename string, inRecs [][]string) {
-	var vcell *xlsx.Cell
-	var cell string
+// dexaLayout は骨密度検診のレイアウト
+type dexaLayout struct{}
+
+func (dexaLayout) Key() string {
+	return "dexa"
+}
 
-	recLen := 7 //出力する項目数
-	cRec := make([]string, recLen)
-	var I int
+func (dexaLayout) Source() string {
+	return "181列 骨密度"
+}
 
-	day := time.Now()
+func (dexaLayout) Name() string {
+	return "骨密度検診"
+}
 
-	excelName, _ := filepath.Split(filename)
-	excelName = excelName + "松英会職員骨密度検診データ" + day.Format("20060102") + ".xlsx"
-	excelFile := xlsx.NewFile()
-	xlsx.SetDefaultFont(11, "游ゴシック")
-	sheet, err := excelFile.AddSheet("データ")
-	failOnError(err)
+func (dexaLayout) Columns() []Column {
+	cRec := make([]Column, 7) //出力する項目数
 
 	//タイトル行
-	cRec[0] = "利用日"
-	cRec[1] = "記号"
-	cRec[2] = "番号"
-	cRec[3] = "本人家族"
-	cRec[4] = "カナ氏名"
-	cRec[5] = "生年月日"
-	cRec[6] = "実施金額"
-	//writer.Write(cRec)
-	row := sheet.AddRow()
-	for _, cell = range cRec {
-		vcell = row.AddCell()
-		vcell.Value = cell
-	}
+	cRec[0] = dateCol("利用日")
+	cRec[1] = textCol("記号")
+	cRec[2] = textCol("番号")
+	cRec[3] = textCol("本人家族")
+	cRec[4] = textCol("カナ氏名")
+	cRec[5] = dateCol("生年月日")
+	cRec[6] = numberCol("実施金額", 0)
+	return cRec
+}
 
-	// データ行
-	inRecsMax := len(inRecs)
-	for J := 1; J < inRecsMax; J++ {
-		for I, _ = range cRec {
-			cRec[I] = ""
-		}
+func (dexaLayout) Filter(rec []string) bool {
+	return rec[181] != ""
+}
 
-		//　保険証番号が空欄は、データ出力対象外
-		if inRecs[J][6] != "" {
-			if inRecs[J][181] != "" {
-				// 0.利用日
-				cRec[0] = strings.Replace((inRecs[J][4]), "-", "/", -1)
+func (dexaLayout) Map(rec []string) []string {
+	cRec := make([]string, 7) //出力する項目数
 
-				// 1.記号
-				cRec[1] = inRecs[J][5]
+	// 0.利用日
+	cRec[0] = strings.Replace((rec[4]), "-", "/", -1)
 
-				// 2.番号
-				cRec[2] = inRecs[J][6]
+	// 1.記号
+	cRec[1] = rec[5]
 
-				// 3.本人家族
-				if kazokuCheck(inRecs[J][3]) {
-					cRec[3] = "1" // 家族
-				} else {
-					cRec[3] = "0" // 本人
-				}
+	// 2.番号
+	cRec[2] = rec[6]
 
-				// 4.カナ氏名（半角）
-				cRec[4] = inRecs[J][7]
+	// 3.本人家族
+	if kazokuCheck(rec[3]) {
+		cRec[3] = "1" // 家族
+	} else {
+		cRec[3] = "0" // 本人
+	}
 
-				// 5.生年月日
-				cRec[5] = WaToSeireki(inRecs[J][9])
+	// 4.カナ氏名（半角）
+	cRec[4] = rec[7]
 
-				// 6.実施金額
-				cRec[6] = "3000"
+	// 5.生年月日
+	cRec[5] = WaToSeireki(rec[9])
 
-				//writer.Write(cRec)
-				row = sheet.AddRow()
-				for _, cell = range cRec {
-					// sheet.Cell(r, c).Value = cell
-					vcell = row.AddCell()
-					vcell.Value = cell
-				}
-			}
-		}
-	}
+	// 6.実施金額
+	cRec[6] = "3000"
 
-	//writer.Flush()
-	err = excelFile.Save(excelName)
-	failOnError(err)
+	return cRec
 }
 
 func WaToSeireki(nen string) string {
@@ -1399,7 +1144,7 @@ func sei(s string) string {
 	} else if s == "女" {
 		return "2"
 	} else {
-		log.Print("性別エラー\r\n")
+		logWarn("性別エラー", "value", s)
 		return "err"
 	}
 }
@@ -1448,22 +1193,6 @@ func cutStrings(s string, maxLen int) string {
 	return s
 }
 
-func syoken(s string) string {
-	s = strings.Replace(s, "　", " ", -1)
-	s = strings.Trim(s, " ")
-
-	for {
-		if utf8.RuneCountInString(s) > 25 {
-			pos := strings.LastIndex(s, " ")
-			s = s[:pos]
-		} else {
-			break
-		}
-	}
-
-	return s
-}
-
 func nyo(s string) string {
 
 	switch s {
@@ -1484,7 +1213,7 @@ func nyo(s string) string {
 	case "5+":
 		s = "+++"
 	default:
-		log.Print("尿変換エラー\r\n")
+		logWarn("尿変換エラー", "value", s)
 		s = "err"
 	}
 
@@ -1513,7 +1242,7 @@ func tokkijiko(s string) string {
 	case "Ｇ":
 		s = "1"
 	default:
-		log.Print("判定有無変換エラー\r\n")
+		logWarn("判定有無変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1543,7 +1272,7 @@ func syokenumu(s string) string {
 	case "Ｈ":
 		s = "1"
 	default:
-		log.Print("判定有無変換エラー\r\n")
+		logWarn("判定有無変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1582,12 +1311,32 @@ func kekka(s string) string {
 	case "G":
 		s = "6"
 	default:
-		log.Print("結果区分変換エラー\r\n")
+		logWarn("結果区分変換エラー", "value", s)
 		s = "err"
 	}
 	return s
 }
 
+func bensenketsu(rec []string) string {
+	// 便潜血２日法の結果区分
+	// どちらかの日が陽性なら要再検（要精密検査）
+	d1 := nyo(rec[41])
+	d2 := nyo(rec[42])
+
+	switch {
+	case d1 == "err" || d2 == "err":
+		return "err"
+	case strings.HasPrefix(d1, "+") && d1 != "+-":
+		return "4"
+	case strings.HasPrefix(d2, "+") && d2 != "+-":
+		return "4"
+	case d1 == "" && d2 == "":
+		return ""
+	default:
+		return "1"
+	}
+}
+
 func rank(v string) int {
 	r := 0
 	switch v {
@@ -1610,7 +1359,7 @@ func rank(v string) int {
 	default:
 		r = 0
 		//log.Print("判定ランクエラー\r\n")
-		log.Printf("判定ランクエラーa%sa\r\n",v)
+		logWarn("判定ランクエラー", "value", v)
 	}
 	return r
 }
@@ -1634,7 +1383,7 @@ func rankS(v int) string {
 		r = "要治療"
 	default:
 		r = "err"
-		log.Print("判定ランクコメントエラー\r\n")
+		logWarn("判定ランクコメントエラー", "value", v)
 	}
 	return r
 }
@@ -1659,7 +1408,7 @@ func yesNo(s string) string {
 	case "いいえ":
 		s = "2"
 	default:
-		log.Print("はいいいえ変換エラー\r\n")
+		logWarn("はいいいえ変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1694,7 +1443,7 @@ func eat2(s string) string {
 	case "ほとんどかめない":
 		s = "3"
 	default:
-		log.Print("かんで食べる変換エラー\r\n")
+		logWarn("かんで食べる変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1712,7 +1461,7 @@ func drink(s string) string {
 	case "ほとんど摂取しない":
 		s = "3"
 	default:
-		log.Print("間食あまい飲み物変換エラー\r\n")
+		logWarn("間食あまい飲み物変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1740,7 +1489,7 @@ func sake(s string) string {
 	case "飲まない":
 		s = "8"
 	default:
-		log.Print("お酒変換エラー\r\n")
+		logWarn("お酒変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1762,7 +1511,7 @@ func sakeryo(s string) string {
 	case "５合以上":
 		s = "5"
 	default:
-		log.Print("飲酒量変換エラー\r\n")
+		logWarn("飲酒量変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1784,7 +1533,7 @@ func seikatsu(s string) string {
 	case "６ヶ月以上":
 		s = "5"
 	default:
-		log.Print("生活習慣改善変換エラー\r\n")
+		logWarn("生活習慣改善変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1802,7 +1551,7 @@ func nyoNotReason(s string) string {
 	case "その他":
 		s = "3"
 	default:
-		log.Print("未実施の場合その理由変換エラー\r\n")
+		logWarn("未実施の場合その理由変換エラー", "value", s)
 		s = "err"
 	}
 	return s
@@ -1820,7 +1569,7 @@ func tabako(s string) string {
 	case "いいえ":
 		s = "3"
 	default:
-		log.Print("たばこ変換エラー\r\n")
+		logWarn("たばこ変換エラー", "value", s)
 		s = "err"
 	}
 	return s
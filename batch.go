@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// inputFile は読み込んだ抽出データ１つ分
+type inputFile struct {
+	Path     string // ファイル名
+	Rows     int    // データ行数（見出しを除く）
+	Same     int    // 前の抽出データと同じ受診・同じ内容のため除いた行数
+	Replaced int    // 前の抽出データと同じ受診・違う内容のため置き換えた行数
+}
+
+// inputs は実行中に読み込んだ抽出データの一覧
+var inputs []inputFile
+
+// runResult は出力フォルダ１つ分の変換結果
+type runResult struct {
+	Inputs   []string      // 抽出データ
+	OutDir   string        // 出力フォルダ
+	Counts   []layoutCount // 検診ごとの出力件数
+	Checks   int           // チェック結果の件数
+	Missings int           // 必須項目の未実施の件数
+
+	exams []examRecord // 健診結果（未受診者の出力に使う）
+}
+
+// layoutCount は検診１つの出力件数
+type layoutCount struct {
+	Name string
+	Rows int
+}
+
+// 抽出データの列の数（変換で使う最後の列は 181）
+const extractColumns = 182
+
+// inputArgs は引数の抽出データのファイル名を返す（無ければ終了する）
+// フォルダを指定した場合はフォルダ内の .txt ファイル（設定の input.pattern）を名前の順に読む
+// フォルダ内のログ（log.txt など）と、見出しの列が抽出データより少ないファイルは読まない
+func inputArgs(args []string) []string {
+	var paths []string
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err != nil {
+			fmt.Println("抽出データがありません " + arg)
+			logError("抽出データがありません", "path", arg)
+			exit(2)
+		}
+		if !isDir(arg) {
+			paths = append(paths, arg)
+			continue
+		}
+
+		pattern := conf.Input.Pattern
+		if pattern == "" {
+			pattern = "*.txt"
+		}
+		files, err := filepath.Glob(filepath.Join(arg, pattern))
+		failOnError(err)
+		sort.Strings(files)
+		found := 0
+		for _, file := range files {
+			switch {
+			case isDir(file) || isLogFile(file):
+			case !isExtract(file):
+				notice("抽出データではないため読み込みません " + file)
+			default:
+				paths = append(paths, file)
+				found++
+			}
+		}
+		if found == 0 {
+			notice("フォルダに抽出データ（" + pattern + "）がありません " + arg)
+		}
+	}
+
+	if len(paths) == 0 {
+		fmt.Println("抽出データのファイルを指定してください（NwToShokuin.exe help で使い方を表示）")
+		logError("抽出データの指定がありません")
+		exit(2)
+	}
+	return paths
+}
+
+// logPattern はこのプログラムのログ（log.txt と古いログ log.1.txt …）の名前
+var logPattern = regexp.MustCompile(`^log(\.[0-9]+)?\.txt$`)
+
+// isLogFile はこのプログラムが書いたログのファイルか
+func isLogFile(path string) bool {
+	if conf.Log.Path != "" && filepath.Base(path) == filepath.Base(conf.Log.Path) {
+		return true
+	}
+	return logPattern.MatchString(filepath.Base(path))
+}
+
+// isExtract は見出しの行の列の数が抽出データと同じかそれ以上のファイルか
+func isExtract(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(transform.NewReader(file, japanese.ShiftJIS.NewDecoder())).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	return len(strings.Split(strings.TrimRight(line, "\r\n"), "\t")) >= extractColumns
+}
+
+// baseName は拡張子を除いたファイル名
+func baseName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// findInput は読み込んだ抽出データの情報を返す
+func findInput(path string) inputFile {
+	for _, in := range inputs {
+		if in.Path == path {
+			return in
+		}
+	}
+	return inputFile{Path: path}
+}
+
+// writeRunReport は複数の抽出データを変換した結果（抽出データごとの行数・出力フォルダごとの件数）を出力する
+func writeRunReport(filename string, results []runResult) {
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "実行結果", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+
+	sheet, err := excelFile.AddSheet("抽出データ")
+	failOnError(err)
+	addRow(sheet, []string{"抽出データ", "行数", "重複（同じ内容）", "重複（置き換え）", "出力フォルダ"})
+	for _, r := range results {
+		for _, path := range r.Inputs {
+			in := findInput(path)
+			addRow(sheet, []string{path, strconv.Itoa(in.Rows), strconv.Itoa(in.Same), strconv.Itoa(in.Replaced), r.OutDir})
+		}
+	}
+
+	sheet, err = excelFile.AddSheet("出力")
+	failOnError(err)
+	addRow(sheet, []string{"出力フォルダ", "出力", "件数"})
+	for _, r := range results {
+		for _, c := range r.Counts {
+			addRow(sheet, []string{r.OutDir, c.Name + "データ", strconv.Itoa(c.Rows)})
+		}
+		addRow(sheet, []string{r.OutDir, "チェック結果", strconv.Itoa(r.Checks)})
+		addRow(sheet, []string{r.OutDir, "必須項目の未実施", strconv.Itoa(r.Missings)})
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+	logInfo("実行結果", "path", excelName, "inputs", len(inputs), "folders", len(results))
+}
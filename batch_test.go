@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestIsLogFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"log.txt", true},
+		{filepath.Join("in", "log.1.txt"), true},
+		{"log.12.txt", true},
+		{"logbook.txt", false},
+		{"04.txt", false},
+		{"log.txt.bak", false},
+	}
+	for _, tt := range tests {
+		if got := isLogFile(tt.path); got != tt.want {
+			t.Errorf("isLogFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInputArgsFolder(t *testing.T) {
+	dir := t.TempDir()
+	header := make([]string, extractColumns)
+	for I := range header {
+		header[I] = "項目"
+	}
+	extract, err := japanese.ShiftJIS.NewEncoder().String(strings.Join(header, "\t") + "\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"05.txt":    extract,
+		"04.txt":    extract,
+		"log.txt":   "2026/10/19 10:00:00 INFO  id Start args=\"a b\"\r\n",
+		"log.1.txt": "",
+		"memo.txt":  "メモ\r\n",
+		"04.xlsx":   "",
+	}
+	for name, s := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := inputArgs([]string{dir})
+	want := []string{filepath.Join(dir, "04.txt"), filepath.Join(dir, "05.txt")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("inputArgs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/tealeg/xlsx"
+)
+
+// checkResult はチェックで見つかった問題１件
+type checkResult struct {
+	Layout  string // 検診名
+	Key     string // 事業所記号-証番号
+	Name    string // カナ氏名
+	Field   string // 項目名
+	Value   string // 値
+	Message string // 内容
+}
+
+// checks は実行中に見つかった問題の一覧
+var checks []checkResult
+
+// exclusion は検診の出力から除いた行１件
+type exclusion struct {
+	Layout string // 検診名
+	Reason string // 理由
+}
+
+// exclusions は実行中に検診の出力から除いた行の一覧
+var exclusions []exclusion
+
+// addExclusion は検診の出力から除いた行を一覧に追加する
+func addExclusion(layout string, reason string) {
+	exclusions = append(exclusions, exclusion{layout, reason})
+}
+
+// addCheck は問題を一覧に追加してログにも出力する
+func addCheck(layout string, rec []string, field string, value string, message string) {
+	addCheckKey(layout, rec[5]+"-"+rec[6], rec[7], field, value, message)
+}
+
+// addCheckKey は入力ファイルの行が無い問題（履歴との比較など）を一覧に追加する
+func addCheckKey(layout string, key string, name string, field string, value string, message string) {
+	c := checkResult{
+		Layout:  layout,
+		Key:     key,
+		Name:    name,
+		Field:   field,
+		Value:   value,
+		Message: message,
+	}
+	checks = append(checks, c)
+	logWarn(c.Message, "layout", c.Layout, "key", c.Key, "name", c.Name, "field", c.Field, "value", c.Value)
+}
+
+// writeChecks はチェック結果をエクセルファイルに出力する（問題が無ければ作らない）
+func writeChecks(filename string) {
+	if len(checks) == 0 {
+		return
+	}
+
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "チェック結果", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("チェック結果")
+	failOnError(err)
+
+	addRow(sheet, []string{"検診", "記号-番号", "カナ氏名", "項目", "値", "内容"})
+	for _, c := range checks {
+		addRow(sheet, []string{c.Layout, c.Key, c.Name, c.Field, c.Value, c.Message})
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+}
+
+// eligible は検診の対象者の条件（性別・年齢）を満たすか調べる
+// 満たさない場合はチェック結果に追加し、除外する設定なら false を返す
+func eligible(key string, name string, rec []string) bool {
+	rule, ok := conf.Eligibility[key]
+	if !ok {
+		return true
+	}
+
+	ng := false
+	reason := ""
+	if rule.Sei != "" && sei(rec[8]) != rule.Sei {
+		addCheck(name, rec, "性別", rec[8], "対象外の性別")
+		ng = true
+		reason = "対象外の性別"
+	}
+
+	if rule.MinAge > 0 || rule.MaxAge > 0 {
+		a, ok := age(WaToSeireki(rec[9]), rec[4])
+		switch {
+		case !ok:
+			addCheck(name, rec, "生年月日", rec[9], "年齢を計算できません")
+		case rule.MinAge > 0 && a < rule.MinAge:
+			addCheck(name, rec, "年齢", strconv.Itoa(a), "対象年齢（"+strconv.Itoa(rule.MinAge)+"歳以上）未満")
+			ng = true
+			if reason == "" {
+				reason = "対象年齢（" + strconv.Itoa(rule.MinAge) + "歳以上）未満"
+			}
+		case rule.MaxAge > 0 && a > rule.MaxAge:
+			addCheck(name, rec, "年齢", strconv.Itoa(a), "対象年齢（"+strconv.Itoa(rule.MaxAge)+"歳以下）超過")
+			ng = true
+			if reason == "" {
+				reason = "対象年齢（" + strconv.Itoa(rule.MaxAge) + "歳以下）超過"
+			}
+		}
+	}
+
+	if ng && rule.Exclude {
+		addExclusion(name, reason)
+		return false
+	}
+	return true
+}
+
+// age は生年月日（yyyy/mm/dd）と受診日（yyyy-mm-dd）から受診日時点の満年齢を返す
+func age(birth string, jushin string) (int, bool) {
+	b, err := time.Parse("2006/01/02", birth)
+	if err != nil {
+		return 0, false
+	}
+	j, err := time.Parse("2006-01-02", jushin)
+	if err != nil {
+		j, err = time.Parse("2006/01/02", jushin)
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	a := j.Year() - b.Year()
+	if j.Month() < b.Month() || (j.Month() == b.Month() && j.Day() < b.Day()) {
+		a--
+	}
+	return a, true
+}
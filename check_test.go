@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAge(t *testing.T) {
+	tests := []struct {
+		birth  string
+		jushin string
+		want   int
+		ok     bool
+	}{
+		{"1975/04/01", "2024-05-10", 49, true},
+		{"1975/05/10", "2024-05-10", 49, true}, // 誕生日当日
+		{"1975/05/11", "2024-05-10", 48, true}, // 誕生日の前日
+		{"1984/02/29", "2024/02/28", 39, true},
+		{"1984/02/29", "2024/02/29", 40, true},
+		{"", "2024-05-10", 0, false},
+		{"1975/04/01", "R6.05.10", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := age(tt.birth, tt.jushin)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("age(%q, %q) = %d, %v, want %d, %v", tt.birth, tt.jushin, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEligibleKey(t *testing.T) {
+	resetState(t)
+	rec := testRec("101", map[int]string{9: "S60.01.01"})
+
+	// 条件は検診の名前（-only と同じ）で引き、チェック結果には検診名で出す
+	conf.Eligibility = map[string]EligibilityRule{"prostate": {Sei: "1", MinAge: 50, Exclude: true}}
+	if eligible("prostate", "前立腺がん検診", rec) {
+		t.Errorf("eligible(prostate) = true for a 39-year-old man")
+	}
+	if len(checks) != 1 || checks[0].Layout != "前立腺がん検診" || checks[0].Field != "年齢" {
+		t.Errorf("checks = %v, want one 年齢 check for 前立腺がん検診", checks)
+	}
+	if !eligible("uterine", "子宮がん検診", rec) {
+		t.Errorf("eligible(uterine) = false without a rule")
+	}
+}
+
+func TestConfigProblemsEligibilityKey(t *testing.T) {
+	c := defaultConfig()
+	c.Eligibility["前立腺がん検診"] = EligibilityRule{Sei: "1"}
+
+	problems := configProblems(c)
+	found := false
+	for _, p := range problems {
+		if strings.Contains(p, "前立腺がん検診") {
+			found = true
+		} else if strings.Contains(p, "eligibility") {
+			t.Errorf("configProblems reports a default rule: %s", p)
+		}
+	}
+	if !found {
+		t.Errorf("configProblems does not report an unknown eligibility key")
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// command はサブコマンド
+type command struct {
+	name string
+	args string // 引数（使い方に表示する）
+	desc string
+	run  func(args []string)
+}
+
+func commands() []command {
+	return []command{
+		{"convert", "[-only 検診 | -profile 名前] [-each] [-out フォルダ] 抽出データ・フォルダ…", "変換して出力ファイルを作る（サブコマンドを省略した場合）", runConvert},
+		{"validate", "[-only 検診 | -profile 名前] 抽出データ・フォルダ…", "変換せずに対象者・入力内容のチェックだけする", runValidate},
+		{"summary", "[-only 検診 | -profile 名前] 抽出データ・フォルダ…", "検診ごとの件数・チェック件数を表示する", runSummary},
+		{"diff", "前回 今回", "前回の出力（または前年度の履歴）と比べる", runDiff},
+		{"config", "check", "設定ファイルの内容を確認する", runConfig},
+		{"history", "記号-番号", "受診者の健診結果の履歴を表示する", runHistory},
+	}
+}
+
+// runCommand は引数の最初のサブコマンドを実行する
+// サブコマンドで無ければ（ドロップされたファイルなど）convert として実行する
+func runCommand(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage()
+			return
+		}
+		for _, c := range commands() {
+			if c.name == args[0] {
+				logInfo("サブコマンド", "command", c.name)
+				c.run(args[1:])
+				return
+			}
+		}
+	}
+	runConvert(args)
+}
+
+// usage はサブコマンドと検診（レイアウト）の一覧を表示する
+func usage() {
+	fmt.Println("使い方: NwToShokuin.exe [サブコマンド] [-config 設定ファイル] 引数")
+	fmt.Println("        抽出データを exe にドロップすると convert を実行する")
+	fmt.Println()
+	fmt.Println("サブコマンド:")
+	for _, c := range commands() {
+		fmt.Printf("  %-9s %-48s %s\n", c.name, c.args, c.desc)
+	}
+	fmt.Println()
+	fmt.Println("検診（-only で指定する名前・入力ファイルの列 0始まり）:")
+	for _, l := range layouts() {
+		fmt.Printf("  %-11s %-10s %s\n", l.Key(), l.Name(), l.Source())
+	}
+	fmt.Println()
+	fmt.Println("各サブコマンドのオプションは NwToShokuin.exe サブコマンド -h で表示する")
+}
+
+// cmdFlags はサブコマンドの引数の解析（-config・-log-level・-log-format は全サブコマンド共通）
+type cmdFlags struct {
+	*flag.FlagSet
+	config    *string
+	logLevel  *string
+	logFormat *string
+}
+
+func newFlags(name string) cmdFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return cmdFlags{
+		FlagSet:   fs,
+		config:    fs.String("config", configPath(), "設定ファイル"),
+		logLevel:  fs.String("log-level", "", "ログのレベル debug/info/warn/error"),
+		logFormat: fs.String("log-format", "", "ログの形式 text/json"),
+	}
+}
+
+// parse は引数を解析して設定ファイルを読み込み、残りの引数を返す
+func (f cmdFlags) parse(args []string) []string {
+	err := f.Parse(args)
+	failOnError(err)
+
+	// 設定ファイル読込
+	conf = loadConfig(*f.config)
+	if *f.logLevel != "" {
+		conf.Log.Level = *f.logLevel
+	}
+	if *f.logFormat != "" {
+		conf.Log.Format = *f.logFormat
+	}
+	return f.Args()
+}
+
+// layoutFlags は出力する検診を選ぶ -only・-profile を登録する（convert・validate・summary 共通）
+func (f cmdFlags) layoutFlags() (only *string, profile *string) {
+	only = f.String("only", "", "出力する検診（gastric,dexa のようにカンマ区切り）")
+	profile = f.String("profile", "", "出力する検診を設定ファイルのプロファイルで選ぶ")
+	return only, profile
+}
+
+// selectLayouts は -only で指定した検診のレイアウトを返す（空欄なら全部）
+func selectLayouts(only string) ([]Layout, error) {
+	if only == "" {
+		return layouts(), nil
+	}
+
+	var ls []Layout
+	for _, key := range strings.Split(only, ",") {
+		key = strings.TrimSpace(key)
+		l := layoutByKey(key)
+		if l == nil && contains(layoutKeys(), key) {
+			// 大腸がん・肺がんを出力しない設定など
+			notice("設定で出力しない検診のため出力しません " + key)
+			continue
+		}
+		if l == nil {
+			return nil, fmt.Errorf("検診の名前が違います %s（%s）", key, strings.Join(layoutKeys(), ","))
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
+
+// layoutByKey は検診の名前（gastric など）のレイアウトを返す（無ければ nil）
+func layoutByKey(key string) Layout {
+	for _, l := range layouts() {
+		if l.Key() == key {
+			return l
+		}
+	}
+	return nil
+}
+
+// chooseLayouts は出力する検診のレイアウトを返す
+// -only・-profile・設定ファイルの既定のプロファイルの順に見て、どれも無ければ全部
+func chooseLayouts(only string, profile string) ([]Layout, error) {
+	if only != "" {
+		return selectLayouts(only)
+	}
+	if profile == "" {
+		profile = conf.Layouts.Profile
+	}
+	if profile == "" {
+		return layouts(), nil
+	}
+
+	keys, ok := conf.Layouts.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("設定ファイルにプロファイルがありません %s", profile)
+	}
+	logInfo("プロファイル", "profile", profile, "layouts", strings.Join(keys, ","))
+	return selectLayouts(strings.Join(keys, ","))
+}
+
+// chosenLayouts は chooseLayouts の検診を返す。選べない場合は終了コード 2 で終わる
+func chosenLayouts(only string, profile string) []Layout {
+	ls, err := chooseLayouts(only, profile)
+	if err != nil {
+		fmt.Println(err)
+		logError("出力する検診を選べません", "err", err)
+		exit(2)
+	}
+	return ls
+}
+
+// notice は利用者へのお知らせを画面とログに出す
+func notice(msg string) {
+	fmt.Println(msg)
+	logWarn(msg)
+}
+
+// runValidate は validate サブコマンド（出力ファイルを作らずにチェック結果を表示する）
+// 問題があれば終了コード 1 で終わる
+func runValidate(args []string) {
+	fs := newFlags("validate")
+	only, profile := fs.layoutFlags()
+	args = fs.parse(args)
+	selected := chosenLayouts(*only, *profile)
+	records := readInput(inputArgs(args)...)
+
+	for _, l := range selected {
+		mapLayout(l, records)
+	}
+
+	for _, c := range checks {
+		fmt.Printf("%s %s %s %s=%s %s\n", c.Layout, c.Key, c.Name, c.Field, c.Value, c.Message)
+	}
+	fmt.Printf("チェック結果 %d件 必須項目の未実施 %d件\n", len(checks), len(missings))
+
+	if len(checks) > 0 {
+		exit(1)
+	}
+}
+
+// runSummary は summary サブコマンド（出力ファイルを作らずに検診ごとの件数を表示する）
+func runSummary(args []string) {
+	fs := newFlags("summary")
+	only, profile := fs.layoutFlags()
+	args = fs.parse(args)
+	selected := chosenLayouts(*only, *profile)
+	records := readInput(inputArgs(args)...)
+
+	fmt.Printf("入力 %d行\n", len(records)-1)
+	for _, l := range selected {
+		n := len(checks)
+		rows := mapLayout(l, records)
+		fmt.Printf("  %-10s %4d件  チェック %d件\n", l.Name(), len(rows), len(checks)-n)
+	}
+	fmt.Printf("必須項目の未実施 %d件 採血時間3.5時間未満 %d件\n", len(missings), len(shortMeals))
+}
+
+// runConfig は config check サブコマンド（設定ファイルの値を確認する）
+func runConfig(args []string) {
+	fs := newFlags("config")
+	args = fs.parse(args)
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Println("使い方: NwToShokuin.exe config [-config 設定ファイル] check")
+		return
+	}
+
+	if _, err := os.Stat(*fs.config); err != nil {
+		fmt.Println("設定ファイルがありません（既定値で動きます） " + *fs.config)
+	} else {
+		fmt.Println("設定ファイル " + *fs.config)
+	}
+
+	problems := configProblems(conf)
+	for _, p := range problems {
+		fmt.Println("  " + p)
+	}
+	if len(problems) == 0 {
+		fmt.Println("問題はありません")
+		return
+	}
+	logWarn("設定ファイルの問題", "count", len(problems))
+	exit(1)
+}
+
+// configProblems は設定の値の問題を返す
+func configProblems(c Config) []string {
+	var p []string
+	oneOf := func(name string, v string, list ...string) {
+		if v != "" && !contains(list, v) {
+			p = append(p, fmt.Sprintf("%s の値が違います %s（%s）", name, v, strings.Join(list, "/")))
+		}
+	}
+
+	oneOf("merge.jushinbi", c.Merge.Jushinbi, "first", "last", "main")
+	oneOf("record.format", c.Record.Format, "json", "ndjson")
+	oneOf("naming.overwrite", c.Naming.Overwrite, "fail", "suffix", "replace")
+	oneOf("log.format", c.Log.Format, "text", "json")
+	oneOf("stats.format", c.Stats.Format, "xlsx", "csv")
+	oneOf("log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error")
+	if c.Naming.File != "" && !strings.Contains(c.Naming.File, "{name}") {
+		p = append(p, "naming.file に {name} がありません（出力ファイルの名前が同じになる） "+c.Naming.File)
+	}
+	if c.Naming.Out != "" && !isDir(c.Naming.Out) {
+		p = append(p, "naming.out のフォルダがありません "+c.Naming.Out)
+	}
+	if c.Notice.Template != "" {
+		if _, err := os.Stat(c.Notice.Template); err != nil {
+			p = append(p, "notice.template のファイルがありません "+c.Notice.Template)
+		}
+	}
+	if c.Department.Column < 0 {
+		p = append(p, fmt.Sprintf("department.column の列が違います %d", c.Department.Column))
+	}
+
+	if _, ok := c.Layouts.Profiles[c.Layouts.Profile]; c.Layouts.Profile != "" && !ok {
+		p = append(p, "layouts.profile のプロファイルがありません "+c.Layouts.Profile)
+	}
+	for name, keys := range c.Layouts.Profiles {
+		for _, key := range keys {
+			if !contains(layoutKeys(), key) {
+				p = append(p, "layouts.profiles."+name+" の検診の名前が違います "+key)
+			}
+		}
+	}
+
+	for name, oc := range c.Output {
+		if name != "*" && name != "stats" && !contains(layoutKeys(), name) {
+			p = append(p, "output の検診の名前が違います "+name+"（"+strings.Join(layoutKeys(), ",")+"・*・stats）")
+		}
+		// 固定長の幅は検診ごとに列の数だけ書く（* は全部の検診に合う幅にできない）
+		if len(oc.Widths) > 0 {
+			if name == "*" || name == "stats" {
+				p = append(p, "output."+name+".widths は書けません（検診ごとに書く）")
+			}
+			for _, l := range allLayouts() {
+				if l.Key() == name && len(oc.Widths) != len(l.Columns()) {
+					p = append(p, fmt.Sprintf("output.%s.widths の数（%d）が列の数（%d）と違います", name, len(oc.Widths), len(l.Columns())))
+				}
+			}
+		}
+		oneOf("output."+name+".format", oc.Format, "xlsx", "csv", "fixed")
+		oneOf("output."+name+".encoding", oc.Encoding, "sjis", "utf8")
+		oneOf("output."+name+".quote", oc.Quote, "all", "none")
+		oneOf("output."+name+".newline", oc.Newline, "crlf", "lf")
+	}
+
+	for name, r := range c.Eligibility {
+		if !contains(layoutKeys(), name) {
+			p = append(p, "eligibility の検診の名前が違います "+name+"（"+strings.Join(layoutKeys(), ",")+"）")
+		}
+		oneOf("eligibility."+name+".sei", r.Sei, "1", "2")
+		if r.MaxAge > 0 && r.MinAge > r.MaxAge {
+			p = append(p, "eligibility."+name+" の min_age が max_age より大きい")
+		}
+	}
+
+	for _, col := range append(append([]int{}, c.Lung.Syoken...), c.Lung.Kakutan) {
+		if col < 0 {
+			p = append(p, fmt.Sprintf("lung の列がマイナスです %d", col))
+		}
+	}
+	if c.Lung.Enabled && len(c.Lung.Syoken) == 0 {
+		p = append(p, "lung.enabled なのに lung.syoken（胸部X線所見の列）がありません。肺がん検診の所見が空欄になります")
+	}
+
+	for name, l := range c.Labs {
+		oneOf("labs."+name+".type", l.Type, "PQ", "CD")
+		if len(l.JLAC10) != 17 {
+			p = append(p, "labs."+name+".jlac10 が17桁ではありません "+l.JLAC10)
+		}
+	}
+
+	if c.XML.Enabled {
+		if len(c.XML.Kikan) != 10 {
+			p = append(p, "xml.kikan（健診機関番号）が10桁ではありません "+c.XML.Kikan)
+		}
+		if len(c.XML.Hokensha) != 8 {
+			p = append(p, "xml.hokensha（保険者番号）が8桁ではありません "+c.XML.Hokensha)
+		}
+		if c.XML.Schema != "" && !isDir(c.XML.Schema) {
+			p = append(p, "xml.schema のフォルダがありません "+c.XML.Schema)
+		}
+	}
+
+	if c.History.Enabled && c.History.Path != "" && !isDir(filepath.Dir(c.History.Path)) {
+		p = append(p, "history.path のフォルダがありません "+c.History.Path)
+	}
+
+	sort.Strings(p)
+	return p
+}
+
+// runHistory は history サブコマンド（受診者の履歴を表示する）
+func runHistory(args []string) {
+	args = newFlags("history").parse(args)
+	if len(args) == 0 {
+		fmt.Println("使い方: NwToShokuin.exe history 記号-番号")
+		return
+	}
+
+	if !historyExists() {
+		fmt.Println("履歴データベースがありません " + historyPath())
+		return
+	}
+	db := openHistory()
+	defer db.Close()
+	showHistory(db, args[0])
+}
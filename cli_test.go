@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// layoutKeysOf はレイアウトの検診の名前をカンマでつなぐ
+func layoutKeysOf(ls []Layout) string {
+	var keys []string
+	for _, l := range ls {
+		keys = append(keys, l.Key())
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestSelectLayouts(t *testing.T) {
+	resetState(t)
+
+	tests := []struct {
+		only string
+		want string
+		err  bool
+	}{
+		{"gastric,dexa", "gastric,dexa", false},
+		{" mmg , uterine", "mmg,uterine", false},
+		{"gastric,colorectal", "gastric", false}, // 設定で出力しない検診は除く
+		{"gastric,胃がん検診", "", true},
+		{"", layoutKeysOf(layouts()), false},
+	}
+	for _, tt := range tests {
+		ls, err := selectLayouts(tt.only)
+		if (err != nil) != tt.err {
+			t.Errorf("selectLayouts(%q) err = %v, want error %v", tt.only, err, tt.err)
+			continue
+		}
+		if got := layoutKeysOf(ls); !tt.err && got != tt.want {
+			t.Errorf("selectLayouts(%q) = %s, want %s", tt.only, got, tt.want)
+		}
+	}
+}
+
+func TestChooseLayouts(t *testing.T) {
+	resetState(t)
+	conf.Layouts.Profiles = map[string][]string{
+		"骨密度":  {"dexa"},
+		"がん検診": {"gastric", "uterine", "breast", "prostate", "mmg"},
+		"間違い":  {"dexa", "bone"},
+	}
+
+	tests := []struct {
+		defaultProfile string
+		only, profile  string
+		want           string
+		err            bool
+	}{
+		{"", "", "", layoutKeysOf(layouts()), false},
+		{"", "", "骨密度", "dexa", false},
+		{"", "gastric", "骨密度", "gastric", false}, // -only が優先
+		{"骨密度", "", "", "dexa", false},           // 設定ファイルの既定のプロファイル
+		{"骨密度", "", "がん検診", "gastric,uterine,breast,prostate,mmg", false},
+		{"", "", "無い", "", true},
+		{"", "", "間違い", "", true},
+	}
+	for _, tt := range tests {
+		conf.Layouts.Profile = tt.defaultProfile
+		ls, err := chooseLayouts(tt.only, tt.profile)
+		if (err != nil) != tt.err {
+			t.Errorf("chooseLayouts(%q, %q) err = %v, want error %v", tt.only, tt.profile, err, tt.err)
+			continue
+		}
+		if got := layoutKeysOf(ls); !tt.err && got != tt.want {
+			t.Errorf("chooseLayouts(%q, %q) = %s, want %s", tt.only, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+	if p := configProblems(defaultConfig()); len(p) != 0 {
+		t.Errorf("configProblems(defaultConfig()) = %v", p)
+	}
+
+	tests := []struct {
+		name string
+		set  func(c *Config)
+		want string // 問題の文字に含まれる
+	}{
+		{"merge.jushinbi", func(c *Config) { c.Merge.Jushinbi = "middle" }, "merge.jushinbi の値が違います middle"},
+		{"naming.file", func(c *Config) { c.Naming.File = "健診{date}" }, "naming.file に {name} がありません"},
+		{"naming.out", func(c *Config) { c.Naming.Out = "/存在しないフォルダ" }, "naming.out のフォルダがありません"},
+		{"layouts.profile", func(c *Config) { c.Layouts.Profile = "骨密度" }, "layouts.profile のプロファイルがありません 骨密度"},
+		{"layouts.profiles", func(c *Config) { c.Layouts.Profiles = map[string][]string{"骨密度": {"bone"}} }, "layouts.profiles.骨密度 の検診の名前が違います bone"},
+		{"eligibility.max_age", func(c *Config) { c.Eligibility["uterine"] = EligibilityRule{MinAge: 60, MaxAge: 20} }, "eligibility.uterine の min_age が max_age より大きい"},
+		{"eligibility.sei", func(c *Config) { c.Eligibility["uterine"] = EligibilityRule{Sei: "女"} }, "eligibility.uterine.sei の値が違います 女"},
+		{"log.level", func(c *Config) { c.Log.Level = "trace" }, "log.level の値が違います trace"},
+		{"lung", func(c *Config) { c.Lung.Kakutan = -1 }, "lung の列がマイナスです -1"},
+	}
+	for _, tt := range tests {
+		c := defaultConfig()
+		tt.set(&c)
+		problems := configProblems(c)
+		if len(problems) != 1 || !strings.Contains(problems[0], tt.want) {
+			t.Errorf("%s: configProblems = %v, want %q", tt.name, problems, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// 設定ファイル名（exeと同じフォルダに置く）
+const configName = "NwToShokuin.json"
+
+// Config は設定ファイルの内容
+type Config struct {
+	Merge        MergeConfig        `json:"merge"`        // 複数日受診の統合
+	Colorectal   ColorectalConfig   `json:"colorectal"`   // 大腸がん検診
+	Lung         LungConfig         `json:"lung"`         // 肺がん検診
+	Breast       BreastConfig       `json:"breast"`       // 乳がん検診
+	Findings     FindingsConfig     `json:"findings"`     // 所見
+	Kiou         KiouConfig         `json:"kiou"`         // 既往歴
+	Required     RequiredConfig     `json:"required"`     // 必須項目
+	Plausibility PlausibilityConfig `json:"plausibility"` // 検査値の範囲
+	XML          XMLConfig          `json:"xml"`          // 特定健診XML
+	Record       RecordConfig       `json:"record"`       // 健診結果（JSON）
+	History      HistoryConfig      `json:"history"`      // 健診結果の履歴
+	Layouts      LayoutsConfig      `json:"layouts"`      // 出力する検診
+	Input        InputConfig        `json:"input"`        // 複数の抽出データ
+	Naming       NamingConfig       `json:"naming"`       // 出力先・出力フォルダとファイルの名前
+	Log          LogConfig          `json:"log"`          // ログ
+	Stats        StatsConfig        `json:"stats"`        // 健康統計（衛生委員会向け）
+	Followup     FollowupConfig     `json:"followup"`     // 要精密検査・要再検のリスト
+	Notice       NoticeConfig       `json:"notice"`       // 個人結果通知
+	Department   DepartmentConfig   `json:"department"`   // 受診者の所属（要精密検査・要再検のリスト・個人結果通知）
+
+	Labs        map[string]LabItem         `json:"labs"`        // 検査項目マスタ（キーは項目名。既定のマスタに追加・上書き）
+	Output      map[string]OutputConfig    `json:"output"`      // 出力形式（キーは -only と同じ検診の名前。* は全レイアウト共通、stats は健康統計）
+	Eligibility map[string]EligibilityRule `json:"eligibility"` // 検診ごとの対象者（キーは -only と同じ検診の名前）
+}
+
+// MergeConfig は複数日受診の統合設定
+type MergeConfig struct {
+	Enabled  bool   `json:"enabled"`  // true なら年度内の分割受診を１件にまとめる
+	Jushinbi string `json:"jushinbi"` // 採用する受診日 first:初回 last:最終 main:主受診日
+}
+
+// ColorectalConfig は大腸がん検診の出力設定
+// 健保のレイアウトで大腸がん検診を提出できる場合だけ出力する
+type ColorectalConfig struct {
+	Enabled bool `json:"enabled"` // true なら大腸がん検診データを出力する
+}
+
+// LungConfig は肺がん検診の出力設定と使う入力ファイルの列（0始まり）
+// 健保のレイアウトで肺がん検診を提出できる場合だけ出力する
+// 抽出データの胸部X線所見・喀痰の列は抽出の設定によって変わるため設定で指定する
+type LungConfig struct {
+	Enabled bool  `json:"enabled"` // true なら肺がん検診データを出力する
+	Syoken  []int `json:"syoken"`  // 胸部X線所見の列
+	Kakutan int   `json:"kakutan"` // 喀痰細胞診の判定の列（0 なら喀痰なし）
+}
+
+// BreastConfig は乳がん検診の出力設定
+type BreastConfig struct {
+	Combined bool `json:"combined"` // true なら超音波とマンモを１人１件にまとめる
+}
+
+// EligibilityRule は検診の対象者の条件
+// 年齢は受診日時点の満年齢
+type EligibilityRule struct {
+	Sei     string `json:"sei"`     // 性別 1:男 2:女 空欄:問わない
+	MinAge  int    `json:"min_age"` // 下限年齢（0 なら下限なし）
+	MaxAge  int    `json:"max_age"` // 上限年齢（0 なら上限なし）
+	Exclude bool   `json:"exclude"` // true なら対象外の人を補助金請求から除く
+}
+
+// FindingsConfig は所見の変換設定
+type FindingsConfig struct {
+	Codes  map[string]string `json:"codes"`  // 院内の所見名 → 健保の所見名（コード）
+	Limits map[string]int    `json:"limits"` // 最大文字数（キーは 検診名.項目名 か 検診名。無ければ128文字）
+}
+
+// KiouConfig は既往歴の設定
+type KiouConfig struct {
+	Dictionary map[string]KiouCode `json:"dictionary"` // 病名 → 標準病名・ICD-10（既定の辞書に追加・上書き）
+	Export     bool                `json:"export"`     // true なら既往歴データを別ファイルに出力する
+}
+
+// RequiredConfig は必須項目の設定
+type RequiredConfig struct {
+	Reasons map[string]int `json:"reasons"` // 項目名 → 未実施理由が入っている入力ファイルの列（0始まり）
+}
+
+// PlausibilityConfig は検査値の範囲チェックの設定
+type PlausibilityConfig struct {
+	Ranges       map[string]Range `json:"ranges"`        // 項目名 → 入力範囲（既定の範囲に追加・上書き）
+	BMITolerance float64          `json:"bmi_tolerance"` // 身長・体重からの計算値との許容差
+}
+
+// XMLConfig は特定健診XML（電子的な標準様式）の出力設定
+type XMLConfig struct {
+	Enabled  bool   `json:"enabled"`  // true なら健診データを特定健診XMLでも出力する
+	Kikan    string `json:"kikan"`    // 送付元の健診機関番号（10桁）
+	Hokensha string `json:"hokensha"` // 送付先の保険者番号（8桁）
+	Schema   string `json:"schema"`   // XSDのフォルダ（空欄ならXSDでは検証しない）
+	Xmllint  string `json:"xmllint"`  // XSDの検証に使う xmllint（空欄なら exe と同じフォルダ・PATH から探す）
+}
+
+// RecordConfig は健診結果（項目名・型つきのデータ）の出力設定
+type RecordConfig struct {
+	Format string `json:"format"` // json / ndjson（空欄なら出力しない）
+}
+
+// HistoryConfig は健診結果の履歴（年度をまたいだデータベース）の設定
+type HistoryConfig struct {
+	Enabled bool   `json:"enabled"` // true なら実行ごとに健診結果を履歴に登録する
+	Path    string `json:"path"`    // データベースのファイル（空欄なら exe と同じフォルダの NwToShokuin.db）
+}
+
+// LayoutsConfig は出力する検診の選び方
+// プロファイルは 骨密度だけ・がん検診だけ など、よく使う検診の組に名前をつけたもの
+type LayoutsConfig struct {
+	Profile   string              `json:"profile"`    // 既定で使うプロファイル（空欄なら全部の検診）
+	Profiles  map[string][]string `json:"profiles"`   // プロファイル名 → 検診の名前（gastric・dexa など）
+	KeepEmpty bool                `json:"keep_empty"` // true なら対象者のいない検診もタイトル行だけで出力する
+}
+
+// InputConfig は複数の抽出データ（ファイル・フォルダ）を指定した場合の設定
+type InputConfig struct {
+	Each    bool   `json:"each"`    // true なら抽出データごとに別の出力フォルダに変換する（false なら１つにまとめる）
+	Pattern string `json:"pattern"` // フォルダを指定した場合に読むファイルの名前（空欄なら *.txt）
+}
+
+// NamingConfig は出力先と出力フォルダ・ファイルの名前の設定
+// 名前の {date} は実行日（yyyymmdd）、{time} は実行時刻（hhmmss）、{input} は抽出データのファイル名、
+// {name} は 健診データ・チェック結果 などの出力の名前に置き換える
+type NamingConfig struct {
+	Out       string `json:"out"`       // 出力先のフォルダ（空欄なら抽出データと同じフォルダ）
+	Folder    string `json:"folder"`    // 出力フォルダの名前（空欄なら出力先に直接出力する）
+	File      string `json:"file"`      // 出力ファイルの名前（拡張子は除く）
+	Overwrite string `json:"overwrite"` // 同じ名前があるとき fail:中止 suffix:_2 などをつける replace:上書き
+}
+
+// LogConfig はログの設定
+type LogConfig struct {
+	Path    string `json:"path"`     // ログファイル（空欄なら出力フォルダの log.txt。-each・変換しない場合は exe と同じフォルダ）
+	Format  string `json:"format"`   // text / json（１件１行）
+	Level   string `json:"level"`    // 出力するレベル debug / info / warn / error
+	MaxSize int    `json:"max_size"` // この大きさ（KB）を超えたら古いログに切り替える（0 なら切り替えない）
+	Keep    int    `json:"keep"`     // 残す古いログの数
+}
+
+// StatsConfig は健康統計（年代・性別ごとの判定・肥満・高血圧、喫煙・飲酒、年度ごとの変化）の設定
+type StatsConfig struct {
+	Enabled bool   `json:"enabled"` // true なら変換ごとに健康統計を出力する
+	Format  string `json:"format"`  // xlsx（表ごとのシート）/ csv（表ごとのファイル）
+}
+
+// FollowupConfig は要精密検査・要再検のリスト（総合判定・がん検診の結果が要再検・要治療の人）の設定
+// 対象者と再受診の記録は履歴データベースに登録する（history.enabled が false でも同じファイルを使う）
+type FollowupConfig struct {
+	Enabled bool `json:"enabled"` // true なら変換ごとに要精密検査・要再検のリストを出力する
+}
+
+// DepartmentConfig は受診者の所属の決め方（入力ファイルに所属の列が無い場合は事業所記号から）
+type DepartmentConfig struct {
+	Column int               `json:"column"` // 所属の入力ファイルの列（0 なら names を使う）
+	Names  map[string]string `json:"names"`  // 事業所記号 → 所属
+}
+
+// NoticeConfig は受診者ごとの結果通知（所属ごとのフォルダに１人１ファイル）の設定
+// ひな形のセルの {項目名} を今回の値、{前回:項目名} を前年度以前の最後の受診の値（履歴がある場合）に置き換える
+type NoticeConfig struct {
+	Enabled  bool   `json:"enabled"`  // true なら変換ごとに結果通知を出力する
+	Template string `json:"template"` // ひな形のエクセルファイル（空欄なら既定の様式）
+}
+
+// OutputConfig はレイアウトの出力形式
+type OutputConfig struct {
+	Format   string `json:"format"`   // xlsx / csv / fixed（固定長）
+	Encoding string `json:"encoding"` // csv・固定長の文字コード sjis / utf8
+	Quote    string `json:"quote"`    // csv の " の囲み all:すべて none:囲まない 空欄:必要な項目だけ
+	Newline  string `json:"newline"`  // csv・固定長の改行 crlf / lf
+	Widths   []int  `json:"widths"`   // 固定長の列の幅（バイト数。無ければ列ごとの最大の長さ）
+	Header   bool   `json:"header"`   // true なら固定長でもタイトル行を出す（健保の固定長はデータ行だけのため既定は出さない）
+}
+
+// conf は実行中の設定
+var conf = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Merge: MergeConfig{
+			Enabled:  false,
+			Jushinbi: "first",
+		},
+		Plausibility: PlausibilityConfig{
+			BMITolerance: 0.2,
+		},
+		Log: LogConfig{
+			Format:  "text",
+			Level:   "info",
+			MaxSize: 1024,
+			Keep:    5,
+		},
+		Stats: StatsConfig{
+			Format: "xlsx",
+		},
+		Naming: NamingConfig{
+			Folder:    "松英会職員健診データ{date}",
+			File:      "松英会職員{name}{date}",
+			Overwrite: "suffix",
+		},
+		Eligibility: map[string]EligibilityRule{
+			"prostate": {Sei: "1", MinAge: 50},
+			"uterine":  {Sei: "2", MinAge: 20},
+			"breast":   {Sei: "2"},
+			"mmg":      {Sei: "2", MinAge: 40},
+		},
+	}
+}
+
+// configPath は設定ファイルの既定の場所を返す
+// ドロップで起動されるとカレントディレクトリが定まらないため exe の場所を基準にする
+func configPath() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return configName
+	}
+	return filepath.Join(filepath.Dir(exe), configName)
+}
+
+// loadConfig は設定ファイルを読み込む。ファイルが無ければ既定値を返す
+func loadConfig(path string) Config {
+	c := defaultConfig()
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c
+	}
+	failOnError(err)
+
+	err = json.Unmarshal(b, &c)
+	failOnError(err)
+
+	logInfo("設定ファイル読込", "path", path)
+	return c
+}
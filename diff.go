@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tealeg/xlsx"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// diffLine は比較で見つかった違い１件
+type diffLine struct {
+	Layout string // 検診名
+	Key    string // 記号-番号
+	Name   string // カナ氏名
+	Kind   string // 追加・削除・変更
+	Field  string // 項目名（変更のとき）
+	Old    string // 前回の値
+	New    string // 今回の値
+}
+
+// diffTable は比較する出力ファイル１つ分
+type diffTable struct {
+	header []string
+	keys   []string            // 記号-番号（ファイルの順）
+	rows   map[string][]string // 記号-番号 → 行
+	name   int                 // カナ氏名の列（無ければ -1）
+}
+
+// runDiff は diff サブコマンド
+// 前回・今回の出力フォルダ（またはファイル）か、履歴の２つの年度を比べる
+func runDiff(args []string) {
+	args = newFlags("diff").parse(args)
+	if len(args) != 2 {
+		fmt.Println("使い方: NwToShokuin.exe diff 前回の出力フォルダ 今回の出力フォルダ")
+		fmt.Println("        NwToShokuin.exe diff 前年度 今年度（履歴の比較。例 diff 2023 2024）")
+		return
+	}
+
+	var lines []diffLine
+	var outDir string
+	if isNendo(args[0]) && isNendo(args[1]) {
+		lines = diffHistory(args[0], args[1])
+		// 差分は -out（設定の naming.out）、無ければ履歴データベースと同じフォルダに出力する
+		outDir = conf.Naming.Out
+		if outDir == "" {
+			outDir = filepath.Dir(historyPath())
+		}
+	} else {
+		outDir = args[1]
+		if !isDir(outDir) {
+			outDir, _ = filepath.Split(outDir)
+		}
+		lines = diffOutputs(args[0], args[1], outDir)
+	}
+
+	writeDiffReport(outDir, lines)
+	fmt.Printf("違い %d件\n", len(lines))
+	logInfo("差分", "old", args[0], "new", args[1], "count", len(lines))
+	printOutputs()
+}
+
+// diffOutputs は前回・今回の出力を検診ごとに比べ、追加・変更の行を訂正データに出力する
+func diffOutputs(oldPath string, newPath string, outDir string) []diffLine {
+	// 比べるファイルの組（検診名 → 前回・今回）
+	pairs := map[string][2]string{}
+	if isDir(oldPath) && isDir(newPath) {
+		for _, l := range layouts() {
+			o, n := findOutput(oldPath, l.Name()), findOutput(newPath, l.Name())
+			if o == "" || n == "" {
+				continue
+			}
+			pairs[l.Name()] = [2]string{o, n}
+		}
+	} else {
+		pairs[outputLayoutName(newPath)] = [2]string{oldPath, newPath}
+	}
+
+	var names []string
+	for name := range pairs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []diffLine
+	for _, name := range names {
+		oldT := readTable(pairs[name][0], name)
+		newT := readTable(pairs[name][1], name)
+		l, corrections := diffTables(name, oldT, newT)
+		lines = append(lines, l...)
+
+		if len(corrections) > 0 {
+			writeCorrections(outDir, name, newT.header, corrections)
+		}
+	}
+	return lines
+}
+
+// diffTables は前回・今回のファイルを記号-番号で突き合わせて比べる
+// 数値・日付の列は値で比べる（170 と 170.0 は同じ）
+// 今回追加・変更になった行を訂正データとして返す
+func diffTables(name string, oldT diffTable, newT diffTable) ([]diffLine, [][]string) {
+	var lines []diffLine
+	var corrections [][]string
+
+	types := map[string]Column{}
+	for _, l := range layouts() {
+		if l.Name() == name {
+			for _, col := range l.Columns() {
+				types[col.Name] = col
+			}
+		}
+	}
+
+	for _, key := range newT.keys {
+		n := newT.rows[key]
+		o, ok := oldT.rows[key]
+		if !ok {
+			lines = append(lines, diffLine{Layout: name, Key: key, Name: newT.kana(n), Kind: "追加"})
+			corrections = append(corrections, n)
+			continue
+		}
+
+		changed := false
+		for I, field := range newT.header {
+			ov := oldT.value(o, field)
+			nv := cellAt(n, I)
+			if normalizeCell(types[field], ov) != normalizeCell(types[field], nv) {
+				lines = append(lines, diffLine{name, key, newT.kana(n), "変更", field, ov, nv})
+				changed = true
+			}
+		}
+		if changed {
+			corrections = append(corrections, n)
+		}
+	}
+
+	for _, key := range oldT.keys {
+		if _, ok := newT.rows[key]; !ok {
+			lines = append(lines, diffLine{Layout: name, Key: key, Name: oldT.kana(oldT.rows[key]), Kind: "削除"})
+		}
+	}
+
+	return lines, corrections
+}
+
+// diffHistory は履歴の２つの年度の健診結果を受診者ごとに比べる（各年度の最後の受診）
+func diffHistory(oldYear string, newYear string) []diffLine {
+	if !historyExists() {
+		fmt.Println("履歴データベースがありません " + historyPath())
+		return nil
+	}
+	db := openHistory()
+	defer db.Close()
+
+	o, _ := strconv.Atoi(oldYear)
+	n, _ := strconv.Atoi(newYear)
+
+	var lines []diffLine
+	hist := loadHistory(db, "")
+	for _, p := range sortedKeys(hist) {
+		var oldRec, newRec *examRecord
+		for I := range hist[p] {
+			r := &hist[p][I].Record
+			switch nendo(r.Jushinbi) {
+			case o:
+				oldRec = r
+			case n:
+				newRec = r
+			}
+		}
+
+		switch {
+		case oldRec == nil && newRec != nil:
+			lines = append(lines, diffLine{Layout: "健診", Key: p, Name: newRec.Kana, Kind: "追加"})
+		case oldRec != nil && newRec == nil:
+			lines = append(lines, diffLine{Layout: "健診", Key: p, Name: oldRec.Kana, Kind: "削除"})
+		case oldRec != nil && newRec != nil:
+			for _, d := range recordDiff(*oldRec, *newRec) {
+				lines = append(lines, diffLine{"健診", p, newRec.Kana, "変更", d.Field, d.Old, d.New})
+			}
+		}
+	}
+	return lines
+}
+
+// readTable は検診（name は検診名）の出力ファイル（xlsx・csv・固定長）を読み込む
+func readTable(path string, name string) diffTable {
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		f, err := xlsx.OpenFile(path)
+		failOnError(err)
+		for _, r := range f.Sheets[0].Rows {
+			var row []string
+			for _, c := range r.Cells {
+				row = append(row, xlsxCellText(c, f.Date1904))
+			}
+			rows = append(rows, row)
+		}
+	case ".csv":
+		b, err := os.ReadFile(path)
+		failOnError(err)
+		if !utf8.Valid(b) {
+			b, err = japanese.ShiftJIS.NewDecoder().Bytes(b)
+			failOnError(err)
+		}
+		reader := csv.NewReader(strings.NewReader(string(b)))
+		reader.FieldsPerRecord = -1
+		rows, err = reader.ReadAll()
+		failOnError(err)
+	case ".txt":
+		rows = readFixed(path, name)
+	default:
+		logWarn("比較できないファイルです（xlsx・csv・固定長のみ）", "path", path)
+	}
+
+	t := diffTable{rows: map[string][]string{}, name: -1}
+	if len(rows) == 0 {
+		return t
+	}
+	t.header = rows[0]
+	kigo := t.column("事業所記号", "記号")
+	bango := t.column("証番号", "番号")
+	t.name = t.column("カナ氏名")
+
+	for _, row := range rows[1:] {
+		key := cellAt(row, kigo) + "-" + cellAt(row, bango)
+		// 同じ人が複数行ある場合（分割受診）は２件目から番号をつける
+		for k := 2; t.rows[key] != nil; k++ {
+			key = cellAt(row, kigo) + "-" + cellAt(row, bango) + "#" + strconv.Itoa(k)
+		}
+		t.keys = append(t.keys, key)
+		t.rows[key] = row
+	}
+	return t
+}
+
+// xlsxCellText はセルの値を返す。日付のセルは yyyy/mm/dd にする（表示形式によらない）
+func xlsxCellText(c *xlsx.Cell, date1904 bool) string {
+	if c.Type() == xlsx.CellTypeNumeric && c.IsTime() {
+		if t, err := c.GetTime(date1904); err == nil {
+			return t.Format("2006/01/02")
+		}
+	}
+	return c.String()
+}
+
+// readFixed は固定長の出力ファイルを出力設定の幅・文字コードで読み込む
+// タイトル行はレイアウトの項目名にする（設定の header が true ならファイルの１行目は読まない）
+// 幅を設定していない固定長は列の区切りが分からないため読まない
+func readFixed(path string, name string) [][]string {
+	var cols []Column
+	var oc OutputConfig
+	for _, l := range layouts() {
+		if l.Name() == name {
+			cols = l.Columns()
+			oc = outputConfig(l.Key())
+		}
+	}
+	if cols == nil || len(oc.Widths) != len(cols) {
+		logWarn("固定長の幅の設定が無いため比較できません", "path", path, "layout", name)
+		return nil
+	}
+
+	b, err := os.ReadFile(path)
+	failOnError(err)
+	lines := strings.Split(string(b), "\n")
+	if oc.Header && len(lines) > 0 {
+		lines = lines[1:]
+	}
+
+	rows := [][]string{columnNames(cols)}
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// 幅は文字コードでのバイト数のため、変換前の行を切る
+		row := make([]string, len(cols))
+		pos := 0
+		for I, w := range oc.Widths {
+			end := pos + w
+			if end > len(line) {
+				end = len(line)
+			}
+			field := ""
+			if pos < end {
+				field = line[pos:end]
+			}
+			if oc.Encoding != "utf8" {
+				field, err = japanese.ShiftJIS.NewDecoder().String(field)
+				failOnError(err)
+			}
+			row[I] = strings.TrimSpace(field)
+			pos = end
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// normalizeCell は列の型に従って比べる値をそろえる
+// 数値は書式（小数点以下の0）を除き、日付は yyyy/mm/dd にする。数値・日付にできない値はそのまま
+func normalizeCell(col Column, s string) string {
+	s = strings.TrimSpace(s)
+	switch col.Type {
+	case colNumber:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case colDate:
+		for _, layout := range []string{"2006/01/02", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format("2006/01/02")
+			}
+		}
+	}
+	return s
+}
+
+// column はタイトル行で最初に見つかった項目名の列を返す（無ければ -1）
+func (t diffTable) column(names ...string) int {
+	for _, name := range names {
+		for I, h := range t.header {
+			if h == name {
+				return I
+			}
+		}
+	}
+	return -1
+}
+
+// value は行の項目名の値を返す（項目が無ければ空欄）
+func (t diffTable) value(row []string, field string) string {
+	return cellAt(row, t.column(field))
+}
+
+func (t diffTable) kana(row []string) string {
+	return cellAt(row, t.name)
+}
+
+// cellAt は行の列の値を返す（列が無ければ空欄）
+func cellAt(row []string, I int) string {
+	if I < 0 || I >= len(row) {
+		return ""
+	}
+	return row[I]
+}
+
+// findOutput はフォルダから検診の出力ファイル（xlsx・csv・固定長）を出力ファイルの名前のひな形で探す
+// 複数あれば名前の後のもの（日付の新しいもの）
+func findOutput(dir string, name string) string {
+	found := ""
+	for _, ext := range []string{".xlsx", ".csv", ".txt"} {
+		files, _ := filepath.Glob(filepath.Join(dir, outputPattern(name+"データ")+ext))
+		for _, f := range files {
+			if filepath.Base(f) > filepath.Base(found) {
+				found = f
+			}
+		}
+	}
+	return found
+}
+
+// outputPattern は出力の名前のファイルを探すパターン（日付・時刻は *）
+func outputPattern(name string) string {
+	r := strings.NewReplacer("{name}", name, "{date}", "*", "{time}", "*", "{input}", "*")
+	return r.Replace(fileTemplate())
+}
+
+// outputLayoutName は出力ファイル名から検診名を取り出す
+func outputLayoutName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, l := range layouts() {
+		for _, name := range []string{l.Name() + "データ", l.Name() + "訂正データ"} {
+			if ok, _ := filepath.Match(outputPattern(name), base); ok {
+				return l.Name()
+			}
+		}
+	}
+	return base
+}
+
+// writeCorrections は追加・変更になった行だけを訂正データとして出力する（再提出用）
+func writeCorrections(dir string, name string, header []string, rows [][]string) {
+	var cols []Column
+	key := ""
+	for _, l := range layouts() {
+		if l.Name() == name && len(l.Columns()) == len(header) {
+			cols = l.Columns()
+			key = l.Key()
+		}
+	}
+	if cols == nil {
+		for _, h := range header {
+			cols = append(cols, textCol(h))
+		}
+	}
+
+	w := newTableWriter(dir, name+"訂正データ", outputConfig(key))
+	w.Header(cols)
+	for _, row := range rows {
+		for len(row) < len(cols) {
+			row = append(row, "")
+		}
+		w.Row(cols, row)
+	}
+	w.Close()
+	logInfo("訂正データ", "layout", name, "count", len(rows))
+}
+
+// writeDiffReport は違いの一覧をエクセルファイルに出力する
+func writeDiffReport(dir string, lines []diffLine) {
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("差分")
+	failOnError(err)
+
+	addRow(sheet, []string{"検診", "記号-番号", "カナ氏名", "区分", "項目", "前回", "今回"})
+	for _, d := range lines {
+		addRow(sheet, []string{d.Layout, d.Key, d.Name, d.Kind, d.Field, d.Old, d.New})
+	}
+
+	err = excelFile.Save(outputFile(dir, "差分", ".xlsx"))
+	failOnError(err)
+}
+
+// isNendo は年度（4桁の数字）か調べる
+func isNendo(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil && len(s) == 4
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
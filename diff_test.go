@@ -0,0 +1,105 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCell(t *testing.T) {
+	tests := []struct {
+		col  Column
+		a, b string
+		same bool
+	}{
+		{numberCol("身長", 1), "170", "170.0", true},
+		{numberCol("HｂA1ｃ", 1), "65", "65.0", true},
+		{numberCol("BMI", 1), "22.4", "22.40", true},
+		{numberCol("BMI", 1), "22.4", "22.5", false},
+		{numberCol("腹囲", 1), "", "80.0", false},
+		{dateCol("受診日"), "2024/05/10", "2024-05-10", true},
+		{dateCol("受診日"), "2024/05/10", "2024/05/11", false},
+		{textCol("証番号"), "0101", "101", false},
+		{textCol("所見"), "胃ポリープ", "胃ポリープ", true},
+	}
+	for _, tt := range tests {
+		if got := normalizeCell(tt.col, tt.a) == normalizeCell(tt.col, tt.b); got != tt.same {
+			t.Errorf("normalizeCell(%s) %q・%q same = %v, want %v", tt.col.Name, tt.a, tt.b, got, tt.same)
+		}
+	}
+}
+
+func TestDiffTablesNumberFormat(t *testing.T) {
+	table := func(rows ...[]string) diffTable {
+		dt := diffTable{header: []string{"事業所記号", "証番号", "カナ氏名", "受診日", "身長", "腹囲"}, rows: map[string][]string{}, name: 2}
+		for _, row := range rows {
+			key := row[0] + "-" + row[1]
+			dt.keys = append(dt.keys, key)
+			dt.rows[key] = row
+		}
+		return dt
+	}
+
+	// 健診データの列（身長・腹囲は数値、受診日は日付）
+	oldT := table([]string{"3025", "101", "ｹﾝﾎﾟ ﾀﾛｳ", "2024/05/10", "170", "80"}, []string{"3025", "102", "ｹﾝﾎﾟ ﾊﾅｺ", "2024/05/10", "160", "70"})
+	newT := table([]string{"3025", "101", "ｹﾝﾎﾟ ﾀﾛｳ", "2024/05/10", "170.0", "80.0"}, []string{"3025", "102", "ｹﾝﾎﾟ ﾊﾅｺ", "2024/05/10", "160.0", "71.0"})
+
+	lines, corrections := diffTables("健診", oldT, newT)
+	if len(lines) != 1 || lines[0].Key != "3025-102" || lines[0].Field != "腹囲" {
+		t.Errorf("diffTables lines = %v", lines)
+	}
+	if len(corrections) != 1 {
+		t.Errorf("diffTables corrections = %d, want 1", len(corrections))
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	resetState(t)
+	conf.Output = map[string]OutputConfig{"gastric": {Widths: []int{6, 10, 8, 8, 1, 30, 1, 10, 1, 128, 20}}}
+	cols := gastricLayout.Columns()
+	row := []string{"000123", "2024/05/10", "3025", "101", "1", "ｹﾝﾎﾟ ﾀﾛｳ", "1", "1975/04/01", "3", "胃ポリープ 胃炎", "レントゲン"}
+
+	// xlsx（日付のセル）・csv・固定長（Shift_JIS・幅の設定）のどれでも同じ値で読む
+	for _, format := range []string{"xlsx", "csv", "fixed"} {
+		dir := t.TempDir()
+		oc := outputConfig("gastric")
+		oc.Format = format
+		w := newTableWriter(dir, "胃がん検診データ", oc)
+		w.Header(cols)
+		w.Row(cols, row)
+		w.Close()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		if len(files) != 1 {
+			t.Fatalf("%s: files = %v", format, files)
+		}
+		dt := readTable(files[0], "胃がん検診")
+		if strings.Join(dt.header, ",") != strings.Join(columnNames(cols), ",") {
+			t.Errorf("%s: header = %v", format, dt.header)
+		}
+		got, ok := dt.rows["3025-101"]
+		if !ok || strings.Join(got, ",") != strings.Join(row, ",") {
+			t.Errorf("%s: row = %q, want %q", format, got, row)
+		}
+	}
+}
+
+func TestReadFixedWithoutWidths(t *testing.T) {
+	resetState(t)
+	cols := gastricLayout.Columns()
+
+	// 幅を設定していない固定長は列の区切りが分からないため読まない
+	dir := t.TempDir()
+	w := newTableWriter(dir, "胃がん検診データ", OutputConfig{Format: "fixed"})
+	w.Header(cols)
+	w.Row(cols, make([]string, len(cols)))
+	w.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	if dt := readTable(files[0], "胃がん検診"); len(dt.keys) != 0 {
+		t.Errorf("readTable = %v, want none", dt.keys)
+	}
+}
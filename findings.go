@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// 健保の文字入力項目の最大文字数（健診データレイアウトの注意事項より）
+const findingsMaxLen = 128
+
+// word は所見の語句１つと、前の語句との区切り（全角・半角の空白）
+type word struct {
+	text string
+	sep  string
+}
+
+// findings は所見の文字を整えて、項目の最大文字数に収める
+// 語句の重複を除き、院内の所見名は健保の所見名（コード）に置き換える
+// 文字数を超えて削った語句はチェック結果に出力する
+func findings(layout string, rec []string, field string, s string) string {
+	var words []word
+	var seen []string
+	for _, w := range splitWords(s) {
+		w.text = findingName(w.text)
+		if !contains(seen, w.text) {
+			seen = append(seen, w.text)
+			words = append(words, w)
+		}
+	}
+
+	return limitWords(layout, rec, field, words)
+}
+
+// freeText は文章の項目（既往歴など）の空白を整えて最大文字数に収める
+// 同じ語句が続けて出てもよい項目なので重複は除かない
+func freeText(layout string, rec []string, field string, s string) string {
+	return limitWords(layout, rec, field, splitWords(s))
+}
+
+// splitWords は全角・半角の空白で語句に分ける
+// 区切りは元の文字のまま残す（続けた空白は最初の１文字にする。"脂質異常　高血圧" の全角は全角のまま）
+func splitWords(s string) []word {
+	var words []word
+	sep, text := "", ""
+	for _, r := range s {
+		if r == ' ' || r == '　' {
+			if text != "" {
+				words = append(words, word{text, sep})
+				sep, text = "", ""
+			}
+			if sep == "" {
+				sep = string(r)
+			}
+			continue
+		}
+		text += string(r)
+	}
+	if text != "" {
+		words = append(words, word{text, sep})
+	}
+	if len(words) > 0 {
+		words[0].sep = ""
+	}
+	return words
+}
+
+// findingName は院内の所見名を健保の所見名（コード）に置き換える
+func findingName(w string) string {
+	if c, ok := conf.Findings.Codes[w]; ok {
+		return c
+	}
+	return w
+}
+
+// findingsLimit は項目の最大文字数を返す
+// 設定の limits は 検診名.項目名（健診.医師の診断 など）、無ければ 検診名（検診のすべての項目）
+func findingsLimit(layout string, field string) int {
+	for _, key := range []string{layout + "." + field, layout} {
+		if n, ok := conf.Findings.Limits[key]; ok && n > 0 {
+			return n
+		}
+	}
+	return findingsMaxLen
+}
+
+// limitWords は語句を元の区切りでつなぎ、最大文字数を超える分は語句の区切りで削る
+func limitWords(layout string, rec []string, field string, words []word) string {
+	limit := findingsLimit(layout, field)
+
+	s := ""
+	for k, w := range words {
+		next := w.text
+		if s != "" {
+			next = s + w.sep + w.text
+		}
+
+		if utf8.RuneCountInString(next) > limit {
+			cut := joinWords(words[k:])
+			if s == "" {
+				// 最初の語句だけで超える場合は途中で切る
+				s = cutStrings(w.text, limit)
+				cut = string([]rune(cut)[limit:])
+			}
+			addCheck(layout, rec, field, cut, "文字数超過（"+fmt.Sprint(limit)+"文字）のため削除")
+			break
+		}
+		s = next
+	}
+
+	return s
+}
+
+// joinWords は語句を元の区切りでつなぐ（先頭の区切りは除く）
+func joinWords(words []word) string {
+	s := ""
+	for k, w := range words {
+		if k > 0 {
+			s += w.sep
+		}
+		s += w.text
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestFindings(t *testing.T) {
+	resetState(t)
+	conf.Findings.Codes = map[string]string{"ポリープ": "胃ポリープ"}
+	conf.Findings.Limits = map[string]int{"胃がん検診.所見": 10, "乳がん検診": 6}
+
+	rec := testRec("101", nil)
+	tests := []struct {
+		layout, field, in string
+		want              string
+		cut               bool
+	}{
+		{"健診", "医師の診断", "脂質異常　高血圧", "脂質異常　高血圧", false},
+		{"健診", "医師の診断", "脂質異常 高血圧", "脂質異常 高血圧", false},
+		{"健診", "医師の診断", "　脂質異常　　高血圧  ", "脂質異常　高血圧", false},
+		{"健診", "医師の診断", "高血圧　脂質異常　高血圧", "高血圧　脂質異常", false},
+		{"胃がん検診", "所見", "ポリープ 胃炎", "胃ポリープ 胃炎", false},
+		{"胃がん検診", "所見", "胃炎　びらん　胃ポリープ", "胃炎　びらん", true},
+		{"乳がん検診", "所見", "腫瘤 石灰化", "腫瘤 石灰化", false},
+		{"乳がん検診", "所見", "腫瘤　石灰化　嚢胞", "腫瘤　石灰化", true},
+		{"乳がん検診", "所見", "乳腺症の疑いあり", "乳腺症の疑い", true},
+	}
+	for _, tt := range tests {
+		checks = nil
+		got := findings(tt.layout, rec, tt.field, tt.in)
+		if got != tt.want {
+			t.Errorf("findings(%s.%s, %q) = %q, want %q", tt.layout, tt.field, tt.in, got, tt.want)
+		}
+		if cut := len(checks) > 0; cut != tt.cut {
+			t.Errorf("findings(%s.%s, %q) cut = %v, want %v", tt.layout, tt.field, tt.in, cut, tt.cut)
+		}
+	}
+}
+
+func TestFindingsLimit(t *testing.T) {
+	resetState(t)
+	conf.Findings.Limits = map[string]int{"胃がん検診.所見": 25, "健診": 100}
+
+	tests := []struct {
+		layout, field string
+		want          int
+	}{
+		{"胃がん検診", "所見", 25},
+		{"健診", "医師の診断", 100},
+		{"健診", "自覚症状所見", 100},
+		{"乳がん検診", "所見", findingsMaxLen},
+	}
+	for _, tt := range tests {
+		if got := findingsLimit(tt.layout, tt.field); got != tt.want {
+			t.Errorf("findingsLimit(%s, %s) = %d, want %d", tt.layout, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	words := splitWords("胃炎　びらん  ポリープ")
+	want := []word{{"胃炎", ""}, {"びらん", "　"}, {"ポリープ", " "}}
+	if len(words) != len(want) {
+		t.Fatalf("splitWords = %v, want %v", words, want)
+	}
+	for I := range want {
+		if words[I] != want[I] {
+			t.Errorf("splitWords[%d] = %v, want %v", I, words[I], want[I])
+		}
+	}
+	if s := joinWords(words); s != "胃炎　びらん ポリープ" {
+		t.Errorf("joinWords = %q", s)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 要精密検査・要再検の対象者のバケット（キーは 記号-番号/受診日/検診）
+var bucketFollowups = []byte("followups")
+
+// followupEntry は要精密検査・要再検の対象者１件（検診ごと）
+type followupEntry struct {
+	Key       string `json:"key"`       // 記号-番号
+	Kana      string `json:"kana"`      // カナ氏名
+	Shozoku   string `json:"shozoku"`   // 所属
+	Jushinbi  string `json:"jushinbi"`  // 受診日（yyyy-mm-dd）
+	Kenshin   string `json:"kenshin"`   // 判定した検診（健診・胃がん検診など）
+	Hantei    string `json:"hantei"`    // 判定（要再検・要治療）
+	Syoken    string `json:"syoken"`    // 所見
+	Run       string `json:"run"`       // 登録した日時
+	Saijushin string `json:"saijushin"` // 再受診日（空欄なら未受診）
+	Kekka     string `json:"kekka"`     // 再受診の判定
+}
+
+// 要精密検査・要再検の出力の列
+var followupColumns = []Column{
+	textCol("記号-番号"),
+	textCol("カナ氏名"),
+	textCol("所属"),
+	dateCol("受診日"),
+	textCol("検診"),
+	textCol("判定"),
+	textCol("所見"),
+	textCol("受診状況"),
+	dateCol("再受診日"),
+	textCol("再受診の判定"),
+}
+
+// writeFollowup は総合判定・がん検診の結果が要再検・要治療の人を要精密検査・要再検のリストに出力する
+// 対象者は履歴データベースに登録し、後の抽出データで同じ検診を受診していれば受診済にする
+// リストには今回の年度の対象者・再受診と、前年度以前の未受診の対象者を出す（該当者が無ければ作らない）
+func writeFollowup(filename string, exams []examRecord, records [][]string) {
+	shozoku := departments(records)
+
+	years := map[int]bool{}
+	var found []followupEntry
+	for _, r := range exams {
+		years[nendo(r.Jushinbi)] = true
+		if followupRank(r.Sogo) {
+			found = append(found, followupEntry{Key: personKey(r), Kana: r.Kana, Shozoku: shozoku[personKey(r)],
+				Jushinbi: r.Jushinbi, Kenshin: "健診", Hantei: r.Sogo, Syoken: r.Shindan})
+		}
+		for _, c := range r.Cancers {
+			if h := cancerRank(c.Result); followupRank(h) {
+				found = append(found, followupEntry{Key: personKey(r), Kana: r.Kana, Shozoku: shozoku[personKey(r)],
+					Jushinbi: c.Jushinbi, Kenshin: c.Name, Hantei: h, Syoken: c.Findings})
+			}
+		}
+	}
+
+	db := openHistory()
+	entries := trackFollowups(db, found, exams)
+	db.Close()
+
+	var list []followupEntry
+	for _, e := range entries {
+		if e.Saijushin == "" || years[nendo(e.Jushinbi)] || years[nendo(e.Saijushin)] {
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "要精密検査・要再検", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("対象者")
+	failOnError(err)
+
+	addRow(sheet, columnNames(followupColumns))
+	done := 0
+	for _, e := range list {
+		status := "未受診"
+		if e.Saijushin != "" {
+			status = "受診済"
+			done++
+		}
+		addTypedRow(sheet, followupColumns, []string{
+			e.Key, e.Kana, e.Shozoku, strings.Replace(e.Jushinbi, "-", "/", -1), e.Kenshin, e.Hantei, e.Syoken,
+			status, strings.Replace(e.Saijushin, "-", "/", -1), e.Kekka,
+		})
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+	logInfo("要精密検査・要再検", "count", len(list), "done", done, "path", excelName)
+}
+
+// trackFollowups は今回の対象者を登録し、未受診の対象者に今回の受診があれば受診済にする
+// 登録済みの対象者をキーの順（受診者・受診日の順）に返す
+func trackFollowups(db *bolt.DB, found []followupEntry, exams []examRecord) []followupEntry {
+	run := runTime.Format("2006-01-02 15:04:05")
+	var entries []followupEntry
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketFollowups)
+		for _, e := range found {
+			key := []byte(e.Key + "/" + e.Jushinbi + "/" + e.Kenshin)
+			// 同じ受診を再度変換した場合は再受診の記録を残す
+			if v := b.Get(key); v != nil {
+				var old followupEntry
+				if err := json.Unmarshal(v, &old); err != nil {
+					return err
+				}
+				e.Saijushin, e.Kekka = old.Saijushin, old.Kekka
+			}
+			e.Run = run
+			v, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, v); err != nil {
+				return err
+			}
+		}
+
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var e followupEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.Saijushin == "" {
+				if day, kekka, ok := laterVisit(e, exams); ok {
+					e.Saijushin, e.Kekka = day, kekka
+					logInfo("再受診", "key", e.Key, "kenshin", e.Kenshin, "jushinbi", e.Jushinbi, "saijushin", day)
+					v, err := json.Marshal(e)
+					if err != nil {
+						return err
+					}
+					if err := b.Put(k, v); err != nil {
+						return err
+					}
+				}
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	failOnError(err)
+
+	return entries
+}
+
+// laterVisit は対象者が判定の後に同じ検診を受診していれば、最初の受診日と判定を返す
+func laterVisit(e followupEntry, exams []examRecord) (string, string, bool) {
+	day, kekka := "", ""
+	for _, r := range exams {
+		if personKey(r) != e.Key {
+			continue
+		}
+		if e.Kenshin == "健診" {
+			if r.Jushinbi > e.Jushinbi && (day == "" || r.Jushinbi < day) {
+				day, kekka = r.Jushinbi, r.Sogo
+			}
+			continue
+		}
+		for _, c := range r.Cancers {
+			if c.Name == e.Kenshin && c.Jushinbi > e.Jushinbi && (day == "" || c.Jushinbi < day) {
+				day, kekka = c.Jushinbi, cancerRank(c.Result)
+			}
+		}
+	}
+	return day, kekka, day != ""
+}
+
+// followupRank は要精密検査・要再検の対象の判定か
+func followupRank(s string) bool {
+	return s == "要再検" || s == "要治療"
+}
+
+// がん検診の結果の凡例（健保のがん検診データレイアウトの結果区分 1～6）
+// 総合判定の並び（sogoOrder）とは 4～6 の順が違う
+var cancerLegend = []string{"", "所見なし", "略正常", "要観察", "要再検", "要治療", "治療中"}
+
+// cancerRank はがん検診の結果（1～6）を結果の凡例の名前にする（凡例に無い値はそのまま）
+func cancerRank(s string) string {
+	if I, err := strconv.Atoi(s); err == nil && I >= 1 && I < len(cancerLegend) {
+		return cancerLegend[I]
+	}
+	return s
+}
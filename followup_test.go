@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestCancerRank(t *testing.T) {
+	tests := []struct {
+		result string
+		want   string
+		follow bool
+	}{
+		{"1", "所見なし", false},
+		{"2", "略正常", false},
+		{"3", "要観察", false},
+		{"4", "要再検", true},
+		{"5", "要治療", true},
+		{"6", "治療中", false},
+		{"", "", false},
+		{"err", "err", false},
+		{"7", "7", false},
+	}
+	for _, tt := range tests {
+		got := cancerRank(tt.result)
+		if got != tt.want {
+			t.Errorf("cancerRank(%q) = %q, want %q", tt.result, got, tt.want)
+		}
+		if f := followupRank(got); f != tt.follow {
+			t.Errorf("followupRank(%q) = %v, want %v", got, f, tt.follow)
+		}
+	}
+}
+
+func TestFollowupRank(t *testing.T) {
+	for _, s := range sogoOrder {
+		want := s == "要再検" || s == "要治療"
+		if got := followupRank(s); got != want {
+			t.Errorf("followupRank(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestBensenketsu(t *testing.T) {
+	tests := []struct {
+		d1, d2 string
+		want   string
+	}{
+		{"－", "－", "1"},
+		{"＋", "－", "4"},
+		{"－", "2+", "4"},
+		{"+-", "－", "1"},
+		{"", "", ""},
+		{"", "－", "1"},
+		{"？", "－", "err"},
+	}
+	for _, tt := range tests {
+		rec := testRec("101", map[int]string{41: tt.d1, 42: tt.d2})
+		got := bensenketsu(rec)
+		if got != tt.want {
+			t.Errorf("bensenketsu(%q, %q) = %q, want %q", tt.d1, tt.d2, got, tt.want)
+		}
+		// 陽性の便潜血は要精密検査・要再検のリストに載る
+		if f := followupRank(cancerRank(got)); f != (tt.want == "4") {
+			t.Errorf("followupRank(cancerRank(%q)) = %v", got, f)
+		}
+	}
+}
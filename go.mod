@@ -4,5 +4,7 @@ go 1.20
 
 require (
 	github.com/tealeg/xlsx v1.0.5 // indirect
+	go.etcd.io/bbolt v1.3.7 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
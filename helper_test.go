@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// resetState は設定と実行中の一覧（チェック結果・除外・出力ファイルなど）を既定に戻す
+// テストの終わりにも戻すため、設定や一覧を使うテストの最初に呼ぶ
+func resetState(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		conf = defaultConfig()
+		checks, exclusions, outputs = nil, nil, nil
+		missings, shortMeals = nil, nil
+		kiouUnknowns = map[string]int{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// testRec は受診日・記号・番号・カナ氏名・性別・生年月日の入った入力ファイルの１行を作る
+// cols は 列（0始まり）→ 値 で、受診者の列も上書きできる
+func testRec(bango string, cols map[int]string) []string {
+	rec := make([]string, extractColumns)
+	rec[4], rec[5], rec[6], rec[7], rec[8], rec[9] = "2024-05-10", "3025", bango, "ｹﾝﾎﾟ ﾀﾛｳ", "男", "S50.04.01"
+	for col, v := range cols {
+		rec[col] = v
+	}
+	return rec
+}
+
+// testInput はタイトル行（空欄）をつけた入力ファイルの行を作る
+func testInput(recs ...[]string) [][]string {
+	return append([][]string{make([]string, extractColumns)}, recs...)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 履歴データベースの既定のファイル名（exeと同じフォルダに置く）
+const historyName = "NwToShokuin.db"
+
+// 健診結果のバケット（キーは 記号-番号/受診日）
+var bucketExams = []byte("exams")
+
+// historyEntry は履歴に登録した健診結果１件
+type historyEntry struct {
+	Run    string     `json:"run"`    // 登録した日時
+	Input  string     `json:"input"`  // 入力ファイル
+	Record examRecord `json:"record"` // 健診結果
+}
+
+// fieldDiff は健診結果の項目１つの違い
+type fieldDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// historyPath は履歴データベースの場所を返す（設定が無ければ exe の場所）
+func historyPath() string {
+	if conf.History.Path != "" {
+		return conf.History.Path
+	}
+	return filepath.Join(filepath.Dir(configPath()), historyName)
+}
+
+// openHistory は履歴データベースを開く（無ければ作る）
+func openHistory() *bolt.DB {
+	db, err := bolt.Open(historyPath(), 0600, &bolt.Options{Timeout: 5 * time.Second})
+	failOnError(err)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketExams); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketFollowups)
+		return err
+	})
+	failOnError(err)
+	return db
+}
+
+// personKey は受診者のキー（記号-番号）
+func personKey(r examRecord) string {
+	return r.Kigo + "-" + r.Bango
+}
+
+// historyKey は履歴のキー（記号-番号/受診日）
+func historyKey(r examRecord) string {
+	return personKey(r) + "/" + r.Jushinbi
+}
+
+// storeHistory は今回の健診結果を履歴に登録する
+// 同じ人・同じ受診日の結果が登録済みで内容が違う場合はチェック結果に出力して置き換える
+func storeHistory(db *bolt.DB, recs []examRecord, input string) {
+	run := runTime.Format("2006-01-02 15:04:05")
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketExams)
+		for _, r := range recs {
+			key := []byte(historyKey(r))
+
+			if v := b.Get(key); v != nil {
+				var old historyEntry
+				if err := json.Unmarshal(v, &old); err != nil {
+					return err
+				}
+				for _, d := range recordDiff(old.Record, r) {
+					addCheckKey("履歴", personKey(r), r.Kana, d.Field, d.Old+" → "+d.New, "前回の提出（"+old.Run+"）と違う")
+				}
+			}
+
+			v, err := json.Marshal(historyEntry{Run: run, Input: input, Record: r})
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	failOnError(err)
+
+	logInfo("履歴に登録", "count", len(recs), "path", historyPath())
+}
+
+// loadHistory は履歴を受診者ごと（受診日の順）に読み込む
+// person を指定するとその人だけ読み込む
+func loadHistory(db *bolt.DB, person string) map[string][]historyEntry {
+	hist := map[string][]historyEntry{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketExams).Cursor()
+
+		prefix := []byte{}
+		if person != "" {
+			prefix = []byte(person + "/")
+		}
+
+		// キーは 記号-番号/受診日 なので同じ人は受診日の順に並んでいる
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = c.Next() {
+			var e historyEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			p := personKey(e.Record)
+			hist[p] = append(hist[p], e)
+		}
+		return nil
+	})
+	failOnError(err)
+
+	return hist
+}
+
+// writeSkipped は前年度に受診して今年度に受診していない人を出力する（該当者が無ければ作らない）
+// 今年度は今回の健診結果の受診日の年度
+func writeSkipped(filename string, db *bolt.DB, recs []examRecord) {
+	year := 0
+	for _, r := range recs {
+		if n := nendo(r.Jushinbi); n > year {
+			year = n
+		}
+	}
+	if year == 0 {
+		return
+	}
+
+	var skipped [][]string
+	hist := loadHistory(db, "")
+	for _, p := range sortedKeys(hist) {
+		entries := hist[p]
+		last := entries[len(entries)-1].Record
+		if nendo(last.Jushinbi) == year-1 {
+			skipped = append(skipped, []string{last.Kigo, last.Bango, last.Kana, strings.Replace(last.Jushinbi, "-", "/", -1)})
+		}
+	}
+	if len(skipped) == 0 {
+		return
+	}
+
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "未受診者データ", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("未受診者")
+	failOnError(err)
+
+	addRow(sheet, []string{"事業所記号", "証番号", "カナ氏名", "前回受診日"})
+	for _, s := range skipped {
+		addRow(sheet, s)
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+	logInfo("未受診者", "nendo", year, "count", len(skipped))
+}
+
+// showHistory は受診者（記号-番号）の履歴を受診日の順に画面に表示する
+func showHistory(db *bolt.DB, person string) {
+	entries := loadHistory(db, person)[person]
+	if len(entries) == 0 {
+		fmt.Println("履歴がありません " + person)
+		return
+	}
+
+	for _, e := range entries {
+		r := e.Record
+		fmt.Printf("%s %s %s 総合判定:%s\n", r.Jushinbi, personKey(r), r.Kana, r.Sogo)
+		for _, res := range r.Results {
+			fmt.Printf("  %s %s %s\n", res.Name, resultText(res), res.Unit)
+		}
+		for _, c := range r.Cancers {
+			fmt.Printf("  %s 結果:%s %s\n", c.Name, c.Result, c.Findings)
+		}
+	}
+}
+
+// recordDiff は２つの健診結果の違う項目を返す
+func recordDiff(old examRecord, new examRecord) []fieldDiff {
+	var diffs []fieldDiff
+	add := func(field string, o string, n string) {
+		if o != n {
+			diffs = append(diffs, fieldDiff{field, o, n})
+		}
+	}
+
+	add("カナ氏名", old.Kana, new.Kana)
+	add("性別", old.Sei, new.Sei)
+	add("生年月日", old.Birth, new.Birth)
+	add("総合判定", old.Sogo, new.Sogo)
+	add("医師の診断", old.Shindan, new.Shindan)
+	add("医師名", old.Ishi, new.Ishi)
+	add("具体的な既往歴", kiouText(old.Kiou), kiouText(new.Kiou))
+	add("自覚症状所見", old.Jikaku, new.Jikaku)
+	add("他覚症状所見", old.Takaku, new.Takaku)
+
+	// 検査・質問票・がん検診は項目名ごとに比べる
+	maps := [][2]map[string]string{
+		{resultMap(old.Results), resultMap(new.Results)},
+		{old.Monshin, new.Monshin},
+		{cancerMap(old.Cancers), cancerMap(new.Cancers)},
+	}
+	for _, m := range maps {
+		for _, k := range unionKeys(m[0], m[1]) {
+			add(k, m[0][k], m[1][k])
+		}
+	}
+
+	return diffs
+}
+
+// resultText は検査結果の値を文字にする
+func resultText(res examResult) string {
+	if res.Value != nil {
+		return strconv.FormatFloat(*res.Value, 'f', -1, 64)
+	}
+	return res.Text
+}
+
+func resultMap(results []examResult) map[string]string {
+	m := map[string]string{}
+	for _, res := range results {
+		m[res.Name] = resultText(res)
+	}
+	return m
+}
+
+func cancerMap(cancers []examCancer) map[string]string {
+	m := map[string]string{}
+	for _, c := range cancers {
+		m[c.Name] = strings.TrimSpace(c.Result + " " + c.Findings)
+	}
+	return m
+}
+
+// unionKeys は２つの map のキーを合わせて並べて返す
+func unionKeys(a map[string]string, b map[string]string) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys は受診者ごとの履歴のキーを並べて返す
+func sortedKeys(hist map[string][]historyEntry) []string {
+	keys := make([]string, 0, len(hist))
+	for k := range hist {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// historyInput は履歴に記録する入力ファイル名（複数ならカンマ区切り）
+func historyInput(paths []string) string {
+	var names []string
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		names = append(names, path)
+	}
+	return strings.Join(names, ",")
+}
+
+// historyExists は履歴データベースがあるか調べる
+func historyExists() bool {
+	_, err := os.Stat(historyPath())
+	return err == nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestRecordDiff(t *testing.T) {
+	v := func(f float64) *float64 { return &f }
+
+	old := examRecord{
+		Kana:    "ｹﾝﾎﾟ ﾀﾛｳ",
+		Sogo:    "要観察",
+		Results: []examResult{{Name: "身長", Value: v(170)}, {Name: "体重", Value: v(65)}},
+		Monshin: map[string]string{"たばこ": "3"},
+		Cancers: []examCancer{{Name: "胃がん検診", Result: "1"}},
+	}
+	new := examRecord{
+		Kana:    "ｹﾝﾎﾟ ﾀﾛｳ",
+		Sogo:    "要再検",
+		Results: []examResult{{Name: "身長", Value: v(170.0)}, {Name: "体重", Value: v(66.5)}, {Name: "腹囲", Value: v(80)}},
+		Monshin: map[string]string{"たばこ": "3"},
+		Cancers: []examCancer{{Name: "胃がん検診", Result: "3", Findings: "胃炎"}},
+	}
+
+	want := []fieldDiff{
+		{"総合判定", "要観察", "要再検"},
+		{"体重", "65", "66.5"},
+		{"腹囲", "", "80"},
+		{"胃がん検診", "1", "3 胃炎"},
+	}
+	got := recordDiff(old, new)
+	if len(got) != len(want) {
+		t.Fatalf("recordDiff = %v, want %v", got, want)
+	}
+	for I := range want {
+		if got[I] != want[I] {
+			t.Errorf("recordDiff[%d] = %v, want %v", I, got[I], want[I])
+		}
+	}
+
+	if d := recordDiff(new, new); len(d) != 0 {
+		t.Errorf("recordDiff(same) = %v", d)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// kiouEntry は既往歴１件
+type kiouEntry struct {
+	Disease string `json:"disease"` // 病名（入力ファイルの表記から括弧書きを除いたもの）
+	Name    string `json:"name"`    // 標準病名（辞書に無ければ Disease と同じ）
+	ICD10   string `json:"icd10"`   // ICD-10 コード（辞書に無ければ空欄）
+	Age     string `json:"age"`     // 発症年齢
+	Status  string `json:"status"`  // 治療状況
+}
+
+// KiouCode は既往歴の辞書の１件
+type KiouCode struct {
+	Name  string `json:"name"`  // 標準病名
+	ICD10 string `json:"icd10"` // ICD-10 コード
+}
+
+// kiouDictionary は既往歴の病名の既定の辞書（設定ファイルの kiou.dictionary で追加・上書きする）
+var kiouDictionary = map[string]KiouCode{
+	"高血圧":        {"高血圧症", "I10"},
+	"高血圧症":       {"高血圧症", "I10"},
+	"糖尿病":        {"糖尿病", "E14"},
+	"脂質異常症":      {"脂質異常症", "E78.5"},
+	"高脂血症":       {"脂質異常症", "E78.5"},
+	"高コレステロール血症": {"脂質異常症", "E78.5"},
+	"高尿酸血症":      {"高尿酸血症", "E79.0"},
+	"痛風":         {"痛風", "M10.9"},
+	"胃潰瘍":        {"胃潰瘍", "K25.9"},
+	"十二指腸潰瘍":     {"十二指腸潰瘍", "K26.9"},
+	"虫垂炎":        {"虫垂炎", "K37"},
+	"胆石":         {"胆石症", "K80.2"},
+	"胆石症":        {"胆石症", "K80.2"},
+	"脳梗塞":        {"脳梗塞", "I63.9"},
+	"狭心症":        {"狭心症", "I20.9"},
+	"心筋梗塞":       {"心筋梗塞", "I21.9"},
+	"喘息":         {"気管支喘息", "J45.9"},
+	"気管支喘息":      {"気管支喘息", "J45.9"},
+	"慢性腎臓病":      {"慢性腎臓病", "N18.9"},
+	"貧血":         {"貧血", "D64.9"},
+	"甲状腺機能低下症":   {"甲状腺機能低下症", "E03.9"},
+	"甲状腺機能亢進症":   {"甲状腺機能亢進症", "E05.9"},
+}
+
+// kiouUnknowns は実行中に見つかった辞書に無い病名と件数（ログには病名ごとに１件だけ出す）
+var kiouUnknowns = map[string]int{}
+
+// parseKiou は入力ファイルの既往歴（病名・年齢・治療状況の１０組）を読み取る
+func parseKiou(rec []string) []kiouEntry {
+	var entries []kiouEntry
+	for k := 0; k < 10; k++ {
+		kp := 101 + (k * 3)
+		kiouB := kiouSet(rec[kp])
+		if kiouB == "" {
+			continue
+		}
+
+		e := kiouEntry{
+			Disease: kiouB,
+			Name:    kiouB,
+			Age:     strings.TrimSpace(string(norm.NFKC.Bytes([]byte(rec[kp+1])))),
+			Status:  strings.TrimSpace(rec[kp+2]),
+		}
+		if c, ok := kiouCode(kiouB); ok {
+			e.Name = c.Name
+			e.ICD10 = c.ICD10
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// kiouCode は病名を辞書で引く（設定ファイルの辞書を優先する）
+func kiouCode(s string) (KiouCode, bool) {
+	if c, ok := conf.Kiou.Dictionary[s]; ok {
+		return c, true
+	}
+	c, ok := kiouDictionary[s]
+	return c, ok
+}
+
+// checkKiou は辞書に無い病名をチェック結果に出し、病名ごとに数える
+func checkKiou(rec []string, entries []kiouEntry) {
+	for _, e := range entries {
+		if _, ok := kiouCode(e.Disease); !ok {
+			kiouUnknowns[e.Disease]++
+			addCheck("健診", rec, "具体的な既往歴", e.Disease, "既往歴辞書に無い病名（入力の表記のまま出力）")
+		}
+	}
+}
+
+// logKiouUnknowns は辞書に無い病名を病名ごとに件数つきでログに出力する
+func logKiouUnknowns() {
+	var names []string
+	for name := range kiouUnknowns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logWarn("既往歴辞書に無い病名", "value", name, "count", kiouUnknowns[name])
+	}
+}
+
+// kiouText は具体的な既往歴の文字（標準病名 年齢才 治療状況）を作る
+// 辞書に無い病名は入力ファイルの表記（括弧書きを除いたもの）のまま
+func kiouText(entries []kiouEntry) string {
+	kiou := ""
+	for _, e := range entries {
+		if kiou == "" {
+			kiou = e.Name
+		} else {
+			kiou = kiou + " " + e.Name
+		}
+
+		if e.Age != "" {
+			kiou = kiou + " " + e.Age + "才"
+		}
+
+		if e.Status != "" {
+			kiou = kiou + " " + e.Status
+		}
+	}
+	return kiou
+}
+
+// kiouSheet は既往歴を１件１行にしたシート（産業保健の事後措置用）
+func kiouSheet(rows []outRow) sheetData {
+	sheet := sheetData{name: "既往歴"}
+
+	//タイトル行
+	sheet.rows = append(sheet.rows, []string{"受診日", "事業所記号", "証番号", "カナ氏名", "生年月日", "病名", "標準病名", "ICD-10", "発症年齢", "治療状況"})
+
+	// データ行
+	for _, row := range rows {
+		rec := row.Rec
+		for _, e := range parseKiou(rec) {
+			sheet.rows = append(sheet.rows, []string{
+				strings.Replace((rec[4]), "-", "/", -1),
+				rec[5],
+				rec[6],
+				string(norm.NFKC.Bytes([]byte(rec[7]))),
+				WaToSeireki(rec[9]),
+				e.Disease,
+				e.Name,
+				e.ICD10,
+				e.Age,
+				e.Status,
+			})
+		}
+	}
+	return sheet
+}
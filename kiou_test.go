@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestKiouText(t *testing.T) {
+	resetState(t)
+	rec := testRec("101", map[int]string{101: "高血圧", 102: "45", 103: "治療中", 104: "ぜんそく", 105: "１０"})
+
+	// 辞書にある病名は標準病名、無い病名は入力の表記のまま
+	entries := parseKiou(rec)
+	if got, want := kiouText(entries), "高血圧症 45才 治療中 ぜんそく 10才"; got != want {
+		t.Errorf("kiouText = %q, want %q", got, want)
+	}
+	if len(entries) != 2 || entries[0].ICD10 != "I10" || entries[1].ICD10 != "" {
+		t.Errorf("parseKiou = %+v", entries)
+	}
+}
+
+func TestCheckKiou(t *testing.T) {
+	resetState(t)
+	rec := testRec("101", map[int]string{101: "ぜんそく", 104: "高血圧"})
+	for I := 0; I < 3; I++ {
+		checkKiou(rec, parseKiou(rec))
+	}
+
+	// 辞書に無い病名だけをチェック結果に出し、病名ごとに数える
+	if len(kiouUnknowns) != 1 || kiouUnknowns["ぜんそく"] != 3 {
+		t.Errorf("kiouUnknowns = %v", kiouUnknowns)
+	}
+	if len(checks) != 3 || checks[0].Field != "具体的な既往歴" || checks[0].Value != "ぜんそく" {
+		t.Errorf("checks = %v", checks)
+	}
+}
+
+func TestKiouSheet(t *testing.T) {
+	resetState(t)
+	inRecs := testInput(testRec("101", map[int]string{101: "高血圧", 104: "胆石"}))
+
+	for _, export := range []bool{false, true} {
+		conf.Kiou.Export = export
+		dir := t.TempDir()
+		writeLayout(filepath.Join(dir, "x"), kenshinLayout{}, inRecs)
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.xlsx"))
+		if len(files) != 1 {
+			t.Fatalf("export %v: files = %v", export, files)
+		}
+		f, err := xlsx.OpenFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// export なら健診データの２枚目のシートに１件１行
+		if !export {
+			if len(f.Sheets) != 1 {
+				t.Errorf("export false: sheets = %d", len(f.Sheets))
+			}
+			continue
+		}
+		if len(f.Sheets) != 2 || f.Sheets[1].Name != "既往歴" || len(f.Sheets[1].Rows) != 3 {
+			t.Fatalf("export true: sheets = %d", len(f.Sheets))
+		}
+		if got := f.Sheets[1].Rows[2].Cells[6].String(); got != "胆石症" {
+			t.Errorf("標準病名 = %q, want 胆石症", got)
+		}
+	}
+}
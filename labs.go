@@ -0,0 +1,57 @@
+package main
+
+// LabItem は検査項目マスタの１件
+// 測定法を変えた場合は設定ファイルの labs でコード・単位・範囲をまとめて置き換える
+type LabItem struct {
+	Source     []int             `json:"source"`      // 入力ファイルの列（0始まり）
+	JLAC10     string            `json:"jlac10"`      // JLAC10（特定健診XMLの項目コード17桁）
+	JLAC11     string            `json:"jlac11"`      // JLAC11（分かる場合）
+	Method     string            `json:"method"`      // 測定法
+	Unit       string            `json:"unit"`        // 単位
+	Type       string            `json:"type"`        // 結果の型 PQ:数値 CD:コード
+	Range      Range             `json:"range"`       // 測定法での入力範囲（max が 0 なら範囲チェックしない）
+	CodeSystem string            `json:"code_system"` // CD の値のコード表
+	Codes      map[string]string `json:"codes"`       // CD の健診データの値 → 結果コード
+}
+
+var nyoCodes = map[string]string{"-": "1", "+-": "2", "+": "3", "++": "4", "+++": "5"}
+
+// labMaster は検査項目マスタの既定値（キーは健診データの項目名）
+// コード・範囲は特定健診XMLの項目コード表による
+var labMaster = map[string]LabItem{
+	"身長":        {Source: []int{11}, JLAC10: "9N001000000000001", Unit: "cm", Type: "PQ", Range: Range{100, 250, 1, ""}},
+	"体重":        {Source: []int{12}, JLAC10: "9N006000000000001", Unit: "kg", Type: "PQ", Range: Range{20, 250, 1, ""}},
+	"BMI":       {Source: []int{13}, JLAC10: "9N011000000000001", Method: "計算", Unit: "kg/m2", Type: "PQ", Range: Range{10, 100, 1, ""}},
+	"腹囲":        {Source: []int{14}, JLAC10: "9N016160100000001", Method: "実測", Unit: "cm", Type: "PQ", Range: Range{40, 250, 1, ""}},
+	"血圧（収縮期）":   {Source: []int{15, 17}, JLAC10: "9A755000000000001", Method: "その他", Unit: "mmHg", Type: "PQ", Range: Range{60, 300, 0, ""}},
+	"血圧（拡張期）":   {Source: []int{16, 18}, JLAC10: "9A765000000000001", Method: "その他", Unit: "mmHg", Type: "PQ", Range: Range{30, 150, 0, ""}},
+	"空腹時中性脂肪":   {Source: []int{19}, JLAC10: "3F015000002327101", Method: "可視吸光光度法", Unit: "mg/dl", Type: "PQ", Range: Range{10, 2000, 0, ""}},
+	"随時中性脂肪":    {Source: []int{179}, JLAC10: "3F015129902327101", Method: "可視吸光光度法", Unit: "mg/dl", Type: "PQ", Range: Range{10, 2000, 0, ""}},
+	"HDL・CO":    {Source: []int{20}, JLAC10: "3F070000002327101", Method: "可視吸光光度法", Unit: "mg/dl", Type: "PQ", Range: Range{10, 500, 0, ""}},
+	"LDL・CO":    {Source: []int{21}, JLAC10: "3F077000002327101", Method: "可視吸光光度法", Unit: "mg/dl", Type: "PQ", Range: Range{20, 1000, 0, ""}},
+	"Non・HDLCO": {JLAC10: "3F069000002391901", Method: "計算", Unit: "mg/dl", Type: "PQ", Range: Range{20, 1000, 0, ""}},
+	"AST(GOT)":  {Source: []int{22}, JLAC10: "3B035000002327201", Method: "紫外吸光光度法", Unit: "U/l", Type: "PQ", Range: Range{0, 1000, 0, ""}},
+	"ALT(GPT)":  {Source: []int{23}, JLAC10: "3B045000002327201", Method: "紫外吸光光度法", Unit: "U/l", Type: "PQ", Range: Range{0, 1000, 0, ""}},
+	"γ・GTP":     {Source: []int{24}, JLAC10: "3B090000002327101", Method: "可視吸光光度法", Unit: "U/l", Type: "PQ", Range: Range{0, 1000, 0, ""}},
+	"空腹時血糖":     {Source: []int{25}, JLAC10: "3D010000001926101", Method: "電位差法", Unit: "mg/dl", Type: "PQ", Range: Range{20, 600, 0, ""}},
+	"HｂA1ｃ":     {Source: []int{26}, JLAC10: "3D046000001920402", Method: "HPLC（NGSP値）", Unit: "%", Type: "PQ", Range: Range{3, 20, 1, ""}},
+	"随時血糖":      {Source: []int{25}, JLAC10: "3D010129901926101", Method: "電位差法", Unit: "mg/dl", Type: "PQ", Range: Range{20, 600, 0, ""}},
+	"尿糖":        {Source: []int{29}, JLAC10: "1A020000000190111", Method: "試験紙法（機械読み取り）", Type: "CD", CodeSystem: "1.2.392.200119.6.2101", Codes: nyoCodes},
+	"尿蛋白":       {Source: []int{30}, JLAC10: "1A010000000190111", Method: "試験紙法（機械読み取り）", Type: "CD", CodeSystem: "1.2.392.200119.6.2101", Codes: nyoCodes},
+	"白血球数":      {Source: []int{31}, JLAC10: "2A010000001930101", Method: "自動血球算定装置", Unit: "/ul", Type: "PQ", Range: Range{500, 100000, 0, ""}},
+	"赤血球数":      {Source: []int{32}, JLAC10: "2A020000001930101", Method: "自動血球算定装置", Unit: "10*4/ul", Type: "PQ", Range: Range{100, 1000, 0, ""}},
+	"血色素量":      {Source: []int{33}, JLAC10: "2A030000001930101", Method: "自動血球算定装置", Unit: "g/dl", Type: "PQ", Range: Range{2, 25, 1, ""}},
+	"ヘマトクリット":   {Source: []int{34}, JLAC10: "2A040000001930102", Method: "自動血球算定装置", Unit: "%", Type: "PQ", Range: Range{5, 80, 1, ""}},
+	"血清クレアチニン":  {Source: []int{35}, JLAC10: "3C015000002327101", Method: "可視吸光光度法（酵素法）", Unit: "mg/dl", Type: "PQ", Range: Range{0.1, 30, 2, ""}},
+	"eGFR":      {Source: []int{36}, JLAC10: "8A065000002391901", Method: "計算", Unit: "ml/min/1.73m2", Type: "PQ", Range: Range{1, 300, 1, ""}},
+	"尿酸値":       {Source: []int{40}, JLAC10: "3C020000002327101", Method: "可視吸光光度法（酵素法）", Unit: "mg/dl", Type: "PQ", Range: Range{0.5, 20, 1, ""}},
+}
+
+// labItem は検査項目マスタを引く（設定ファイルの labs を優先する）
+func labItem(name string) (LabItem, bool) {
+	if l, ok := conf.Labs[name]; ok {
+		return l, true
+	}
+	l, ok := labMaster[name]
+	return l, ok
+}
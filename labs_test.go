@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestLabMaster(t *testing.T) {
+	for name, l := range labMaster {
+		if len(l.JLAC10) != 17 {
+			t.Errorf("%s: JLAC10 %q is not 17 digits", name, l.JLAC10)
+		}
+		if l.Type != "PQ" && l.Type != "CD" {
+			t.Errorf("%s: type = %q", name, l.Type)
+		}
+		if l.Type == "CD" && (l.CodeSystem == "" || len(l.Codes) == 0) {
+			t.Errorf("%s: CD without codes", name)
+		}
+	}
+}
+
+func TestLabItem(t *testing.T) {
+	resetState(t)
+	conf.Labs = map[string]LabItem{
+		"HｂA1ｃ": {Source: []int{26}, JLAC10: "3D046000002227101", Method: "酵素法（NGSP値）", Unit: "%", Type: "PQ"},
+		"尿潜血":   {Source: []int{39}, JLAC10: "1A100000000190111", Type: "CD"},
+	}
+
+	// 設定に書いた項目は置き換え、無い項目は追加、書いていない項目は既定のマスタ
+	if l, ok := labItem("HｂA1ｃ"); !ok || l.JLAC10 != "3D046000002227101" || l.Range.Max != 0 {
+		t.Errorf("labItem(HｂA1ｃ) = %+v, %v", l, ok)
+	}
+	if l, ok := labItem("尿潜血"); !ok || l.JLAC10 != "1A100000000190111" {
+		t.Errorf("labItem(尿潜血) = %+v, %v", l, ok)
+	}
+	if l, ok := labItem("身長"); !ok || l.JLAC10 != labMaster["身長"].JLAC10 {
+		t.Errorf("labItem(身長) = %+v, %v", l, ok)
+	}
+	if _, ok := labItem("所見"); ok {
+		t.Errorf("labItem(所見) found")
+	}
+}
+
+func TestValueRange(t *testing.T) {
+	resetState(t)
+	conf.Plausibility.Ranges = map[string]Range{"腹囲": {Min: 50, Max: 150, Decimals: 0, Unit: "cm"}}
+	conf.Labs = map[string]LabItem{"HｂA1ｃ": {Unit: "%", Type: "PQ", Range: Range{Min: 4, Max: 15, Decimals: 1}}}
+
+	tests := []struct {
+		name string
+		want Range
+		ok   bool
+	}{
+		{"腹囲", Range{50, 150, 0, "cm"}, true},  // plausibility.ranges
+		{"HｂA1ｃ", Range{4, 15, 1, "%"}, true},  // labs の範囲（単位は項目の unit）
+		{"身長", Range{100, 250, 1, "cm"}, true}, // 既定のマスタ
+		{"尿糖", Range{}, false},                 // 範囲の無い項目
+		{"カナ氏名", Range{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := valueRange(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("valueRange(%s) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
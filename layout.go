@@ -0,0 +1,426 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Layout は出力ファイル１つ分のレイアウト
+type Layout interface {
+	Name() string              // ファイル名に使う名称（松英会職員○○データ）
+	Key() string               // コマンドラインで指定する名前（--only gastric など）
+	Source() string            // 使う入力ファイルの列（--help で表示する）
+	Columns() []Column         // 列の定義（タイトル行と型）
+	Filter(rec []string) bool  // 出力対象の行なら true
+	Map(rec []string) []string // 入力１行を出力１行に変換する
+}
+
+// colType は出力する列の型
+type colType int
+
+const (
+	colText   colType = iota // 文字（コード類は0埋めのまま文字で出す）
+	colNumber                // 数値
+	colDate                  // 日付（yyyy/mm/dd）
+)
+
+// Column は出力する列の定義
+type Column struct {
+	Name     string
+	Type     colType
+	Decimals int // 数値の小数点以下の桁数
+}
+
+func textCol(name string) Column {
+	return Column{Name: name, Type: colText}
+}
+
+func numberCol(name string, decimals int) Column {
+	return Column{Name: name, Type: colNumber, Decimals: decimals}
+}
+
+func dateCol(name string) Column {
+	return Column{Name: name, Type: colDate}
+}
+
+// columnNames はタイトル行の文字を返す
+func columnNames(cols []Column) []string {
+	names := make([]string, len(cols))
+	for I, c := range cols {
+		names[I] = c.Name
+	}
+	return names
+}
+
+// rowChecker は出力行を検査するレイアウト（必須項目など）
+type rowChecker interface {
+	Check(rec []string, cRec []string)
+}
+
+// layouts は出力するレイアウトの一覧（出力順）
+func layouts() []Layout {
+	ls := []Layout{
+		kenshinLayout{},
+		gastricLayout,
+		uterineLayout,
+	}
+
+	// 乳がんは超音波とマンモを別ファイルにするか１件にまとめるか
+	if conf.Breast.Combined {
+		ls = append(ls, breastCombinedLayout)
+	} else {
+		ls = append(ls, breastLayout)
+	}
+
+	ls = append(ls, prostateLayout)
+	if !conf.Breast.Combined {
+		ls = append(ls, mmgLayout)
+	}
+
+	// 大腸がん・肺がんは健保のレイアウトで提出できる場合だけ（設定で出力する）
+	if conf.Colorectal.Enabled {
+		ls = append(ls, colorectalLayout)
+	}
+	if conf.Lung.Enabled {
+		ls = append(ls, lungLayout)
+	}
+
+	return append(ls, dexaLayout{})
+}
+
+// allLayouts は設定で出力しない検診も含めた全部のレイアウト
+func allLayouts() []Layout {
+	return []Layout{
+		kenshinLayout{},
+		gastricLayout,
+		uterineLayout,
+		breastLayout,
+		prostateLayout,
+		mmgLayout,
+		colorectalLayout,
+		lungLayout,
+		dexaLayout{},
+	}
+}
+
+// layoutKeys は設定・-only で使える検診の名前（設定で出力しない検診も含む）
+func layoutKeys() []string {
+	var keys []string
+	for _, l := range allLayouts() {
+		keys = append(keys, l.Key())
+	}
+	return keys
+}
+
+// sheetAdder はデータのシートの後にシートを追加するレイアウト
+type sheetAdder interface {
+	Sheets(rows []outRow) []sheetData
+}
+
+// sheetData は追加するシート（先頭行がタイトル行）
+type sheetData struct {
+	name string
+	rows [][]string
+}
+
+// outRow は出力した１行と元の入力の行
+type outRow struct {
+	Rec  []string // 入力ファイルの行
+	CRec []string // 出力の行
+}
+
+// writeLayout はレイアウトに従って出力ファイルを作成し、出力した行を返す
+// ファイルの形式（xlsx・csv・固定長）は出力設定による
+// 対象者がいない場合はファイルを作らない（設定の keep_empty が true なら作る）
+func writeLayout(filename string, l Layout, inRecs [][]string) []outRow {
+	rows := mapLayout(l, inRecs)
+	if len(rows) == 0 && !conf.Layouts.KeepEmpty {
+		notice(l.Name() + "データは対象者がいないため出力しません")
+		return rows
+	}
+
+	outDir, _ := filepath.Split(filename)
+	w := newTableWriter(outDir, l.Name()+"データ", outputConfig(l.Key()))
+
+	//タイトル行
+	cols := l.Columns()
+	w.Header(cols)
+
+	// データ行
+	for _, row := range rows {
+		w.Row(cols, row.CRec)
+	}
+
+	// 追加のシート（xlsx なら同じファイル、csv・固定長なら別のエクセルファイル）
+	if s, ok := l.(sheetAdder); ok {
+		for _, sheet := range s.Sheets(rows) {
+			if xw, ok := w.(*xlsxWriter); ok {
+				xw.AddSheet(sheet)
+			} else {
+				writeSheetFile(outDir, sheet)
+			}
+		}
+	}
+
+	w.Close()
+
+	return rows
+}
+
+// mapLayout は入力ファイルの行をレイアウトの出力行に変換する（チェックもする）
+func mapLayout(l Layout, inRecs [][]string) []outRow {
+	var rows []outRow
+	inRecsMax := len(inRecs)
+	for J := 1; J < inRecsMax; J++ {
+		//　保険証番号が空欄は、データ出力対象外
+		if inRecs[J][6] == "" {
+			continue
+		}
+
+		// 変換中の警告に検診・行・受診者をつける
+		row := &rowContext{l.Name(), J + 1, inRecs[J][5] + "-" + inRecs[J][6], inRecs[J][7]}
+		setRow(row)
+
+		rec := inRecs[J]
+		if !l.Filter(rec) {
+			continue
+		}
+		if p, ok := l.(partChecker); ok {
+			// 対象外で除いた検査しか無ければ出力しない
+			if rec = p.CheckParts(rec); !l.Filter(rec) {
+				continue
+			}
+		}
+		if eligible(l.Key(), l.Name(), rec) {
+			cRec := l.Map(rec)
+			if c, ok := l.(rowChecker); ok {
+				c.Check(rec, cRec)
+			}
+			rows = append(rows, outRow{rec, cRec})
+			logDebug("出力", "layout", row.layout, "line", row.line, "key", row.key)
+		}
+	}
+	setRow(nil)
+	return rows
+}
+
+func addRow(sheet *xlsx.Sheet, cRec []string) {
+	row := sheet.AddRow()
+	for _, cell := range cRec {
+		vcell := row.AddCell()
+		vcell.Value = cell
+	}
+}
+
+// addTypedRow は列の定義に従って数値・日付のセルにして行を追加する
+func addTypedRow(sheet *xlsx.Sheet, cols []Column, cRec []string) {
+	row := sheet.AddRow()
+	for I, cell := range cRec {
+		setCell(row.AddCell(), cols[I], cell)
+	}
+}
+
+// setCell はセルに値を入れる。数値・日付にできない値は文字のまま入れる
+func setCell(vcell *xlsx.Cell, col Column, s string) {
+	switch col.Type {
+	case colNumber:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			vcell.SetFloatWithFormat(v, numberFormat(col.Decimals))
+			return
+		}
+	case colDate:
+		if t, err := time.Parse("2006/01/02", s); err == nil {
+			vcell.SetDateWithOptions(t, xlsx.DateTimeOptions{Location: time.UTC, ExcelTimeFormat: "yyyy/mm/dd"})
+			return
+		}
+	}
+	vcell.Value = s
+}
+
+// numberFormat は小数点以下の桁数の表示形式を返す（2 なら 0.00）
+func numberFormat(decimals int) string {
+	if decimals <= 0 {
+		return "0"
+	}
+	return "0." + strings.Repeat("0", decimals)
+}
+
+// formatRow は数値の列を小数点以下の桁数にそろえた行を返す（csv・固定長。xlsx の表示形式と同じ桁）
+// 数値にできない値は文字のまま
+func formatRow(cols []Column, cRec []string) []string {
+	out := make([]string, len(cRec))
+	for I, s := range cRec {
+		out[I] = s
+		if I >= len(cols) || cols[I].Type != colNumber {
+			continue
+		}
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			out[I] = strconv.FormatFloat(v, 'f', cols[I].Decimals, 64)
+		}
+	}
+	return out
+}
+
+// がん検診（検診機関作成分）の列
+var cancerColumns = []Column{
+	textCol("支払先CD"),
+	dateCol("受診日"),
+	textCol("事業所記号"),
+	textCol("証番号"),
+	textCol("資格区分"),
+	textCol("カナ氏名"),
+	textCol("性別"),
+	dateCol("生年月日"),
+	textCol("結果"),
+	textCol("所見"),
+	textCol("検査区分"),
+}
+
+// cancerLayout はがん検診のレイアウト
+// 対象行・結果・所見・検査区分だけが検診ごとに異なる
+type cancerLayout struct {
+	name   string                    // ファイル名に使う名称
+	key    string                    // コマンドラインで指定する名前
+	source string                    // 使う入力ファイルの列
+	filter func(rec []string) bool   // 対象行なら true
+	result func(rec []string) string // 8.結果
+	syoken func(rec []string) string // 9.所見（nil なら空欄）
+	kensa  func(rec []string) string // 10.検査区分（nil なら空欄）
+	parts  []layoutPart              // １行にまとめた検査（対象者の条件を検査ごとにも調べる）
+}
+
+// layoutPart は１行にまとめた検査の１つ（乳がん検診のマンモなど）
+type layoutPart struct {
+	layout cancerLayout // 検査のレイアウト（対象者の条件は key、対象行は filter）
+	cols   []int        // 検査の入力ファイルの列（対象外で除く場合は空欄にする）
+}
+
+// partChecker は１行に複数の検査をまとめたレイアウト
+type partChecker interface {
+	CheckParts(rec []string) []string
+}
+
+// CheckParts は検査ごとの対象者の条件を調べ、除く設定で対象外の検査は列を空欄にした行を返す
+func (l cancerLayout) CheckParts(rec []string) []string {
+	for _, p := range l.parts {
+		if !p.layout.filter(rec) || eligible(p.layout.key, p.layout.name, rec) {
+			continue
+		}
+		r := make([]string, len(rec))
+		copy(r, rec)
+		for _, col := range p.cols {
+			r[col] = ""
+		}
+		rec = r
+	}
+	return rec
+}
+
+func (l cancerLayout) Name() string {
+	return l.name
+}
+
+func (l cancerLayout) Key() string {
+	return l.key
+}
+
+func (l cancerLayout) Source() string {
+	return l.source
+}
+
+func (l cancerLayout) Columns() []Column {
+	cols := make([]Column, len(cancerColumns))
+	copy(cols, cancerColumns)
+	return cols
+}
+
+func (l cancerLayout) Filter(rec []string) bool {
+	return l.filter(rec)
+}
+
+func (l cancerLayout) Map(rec []string) []string {
+	cRec := make([]string, len(cancerColumns)) //出力する項目数
+
+	// 0.支払先CD
+	cRec[0] = "415201"
+
+	// 1.受診日
+	cRec[1] = strings.Replace((rec[4]), "-", "/", -1)
+
+	// 2.事業所記号
+	cRec[2] = rec[5]
+
+	// 3.証番号
+	cRec[3] = rec[6]
+
+	// 4.資格区分
+	if kazokuCheck(rec[3]) {
+		cRec[4] = "1" // 家族
+	} else {
+		cRec[4] = "0" // 本人
+	}
+
+	// 5.カナ氏名
+	cRec[5] = string(norm.NFKC.Bytes([]byte(rec[7])))
+
+	// 6.性別
+	cRec[6] = sei(rec[8])
+
+	// 7.生年月日
+	cRec[7] = WaToSeireki(rec[9])
+
+	// 8.結果
+	cRec[8] = l.result(rec)
+
+	// 9.所見
+	if l.syoken != nil {
+		cRec[9] = findings(l.name, rec, "所見", l.syoken(rec))
+	}
+
+	// 10.検査区分
+	if l.kensa != nil {
+		cRec[10] = l.kensa(rec)
+	}
+
+	return cRec
+}
+
+// kubun は常に同じ検査区分を返す
+func kubun(s string) func(rec []string) string {
+	return func(rec []string) string { return s }
+}
+
+// colValue は設定で指定した列の値を返す（0・範囲外は空欄）
+func colValue(rec []string, col int) string {
+	if col <= 0 || col >= len(rec) {
+		return ""
+	}
+	return rec[col]
+}
+
+// ijo は判定が所見ありか（空欄・Ａ・Ｂ以外）
+func ijo(s string) bool {
+	return s != "" && s != "Ａ" && s != "Ｂ"
+}
+
+// joinCols は rec[from] から n 列の文字をスペースでつなぐ
+func joinCols(rec []string, from int, n int) string {
+	s := ""
+	for k := 0; k < n; k++ {
+		v := rec[from+k]
+
+		if v != "" {
+			if s == "" {
+				s = v
+			} else {
+				s = s + " " + v
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestLayoutsColorectalLung(t *testing.T) {
+	resetState(t)
+
+	has := func(key string) bool {
+		for _, l := range layouts() {
+			if l.Key() == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 既定では大腸がん・肺がんは出力しない
+	if has("colorectal") || has("lung") {
+		t.Errorf("colorectal/lung are emitted by default")
+	}
+
+	conf.Colorectal.Enabled = true
+	conf.Lung.Enabled = true
+	if !has("colorectal") || !has("lung") {
+		t.Errorf("colorectal/lung are not emitted when enabled")
+	}
+}
+
+func TestConfigProblemsLungSyoken(t *testing.T) {
+	c := defaultConfig()
+	c.Lung.Enabled = true
+	c.Lung.Syoken = nil
+
+	found := false
+	for _, p := range configProblems(c) {
+		if strings.Contains(p, "lung.syoken") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("configProblems does not report lung enabled without lung.syoken")
+	}
+}
+
+func TestGastricLayout(t *testing.T) {
+	rec := testRec("101", map[int]string{161: "胃ポリープ", 162: "胃炎", 164: "逆流性食道炎", 166: "萎縮性胃炎"})
+
+	tests := []struct {
+		xray, camera          string
+		result, syoken, kensa string
+	}{
+		{"Ｃ", "", "3", "胃ポリープ 胃炎", "レントゲン"},
+		{"Ａ", "", "1", "", "レントゲン"},
+		{"", "Ｃ", "3", "逆流性食道炎 萎縮性胃炎", "内視鏡"}, // 胃カメラだけ
+		{"", "Ｂ", "2", "", "内視鏡"},             // 所見なしの胃カメラは所見を出さない
+		{"Ｃ", "Ｃ", "3", "胃ポリープ 胃炎", "レントゲン"},  // 両方あればX線（元の変換と同じ）
+	}
+	for _, tt := range tests {
+		rec[78], rec[80] = tt.xray, tt.camera
+		if got := gastricLayout.result(rec); got != tt.result {
+			t.Errorf("%q/%q result = %q, want %q", tt.xray, tt.camera, got, tt.result)
+		}
+		if got := gastricLayout.syoken(rec); got != tt.syoken {
+			t.Errorf("%q/%q syoken = %q, want %q", tt.xray, tt.camera, got, tt.syoken)
+		}
+		if got := gastricLayout.kensa(rec); got != tt.kensa {
+			t.Errorf("%q/%q kensa = %q, want %q", tt.xray, tt.camera, got, tt.kensa)
+		}
+	}
+}
+
+func TestBreastCombinedMmgEligibility(t *testing.T) {
+	resetState(t)
+	conf.Breast.Combined = true
+	conf.Eligibility["mmg"] = EligibilityRule{Sei: "2", MinAge: 40, Exclude: true}
+
+	rec := func(bango string, us string, mmg string) []string {
+		return testRec(bango, map[int]string{7: "ｹﾝﾎﾟ ﾊﾅｺ", 8: "女", 9: "S60.01.01", 94: us, 96: mmg, 176: "石灰化"})
+	}
+	// 39歳。超音波とマンモの人はマンモを除いて出力、マンモだけの人は出力しない
+	inRecs := testInput(rec("101", "Ａ", "Ｃ"), rec("102", "", "Ｃ"))
+
+	rows := mapLayout(breastCombinedLayout, inRecs)
+	if len(rows) != 1 {
+		t.Fatalf("mapLayout rows = %d, want 1", len(rows))
+	}
+	if got := breastCombinedLayout.result(rows[0].Rec); got != "1" {
+		t.Errorf("result = %q, want 1 (ultrasound only)", got)
+	}
+	if got := breastCombinedLayout.syoken(rows[0].Rec); got != "" {
+		t.Errorf("syoken = %q, want none", got)
+	}
+	if inRecs[1][96] != "Ｃ" {
+		t.Errorf("CheckParts changed the input record")
+	}
+	if len(exclusions) != 2 || exclusions[0].Layout != "マンモ検診" {
+		t.Errorf("exclusions = %v, want two マンモ検診", exclusions)
+	}
+
+	// 対象年齢ならマンモもまとめる
+	conf.Eligibility["mmg"] = EligibilityRule{Sei: "2", MinAge: 30, Exclude: true}
+	rows = mapLayout(breastCombinedLayout, inRecs)
+	if len(rows) != 2 || breastCombinedLayout.result(rows[0].Rec) != "3" {
+		t.Errorf("mapLayout with an eligible age = %d rows, want 2 with the mammography result", len(rows))
+	}
+}
+
+func TestSetCell(t *testing.T) {
+	sheet, err := xlsx.NewFile().AddSheet("データ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := sheet.AddRow()
+
+	tests := []struct {
+		col     Column
+		in      string
+		numeric bool
+		format  string
+		want    string
+	}{
+		{dateCol("受診日"), "2024/05/10", true, "yyyy/mm/dd", "45422"},
+		{numberCol("身長", 1), "170", true, "0.0", "170"},
+		{numberCol("HｂA1ｃ", 2), "5.4", true, "0.00", "5.4"},
+		{numberCol("血圧（収縮期）", 0), "120", true, "0", "120"},
+		{textCol("証番号"), "0012", false, "", "0012"}, // 0埋めのコードは文字のまま
+		{numberCol("身長", 1), "－", false, "", "－"},   // 数値にできない値は文字
+		{dateCol("受診日"), "R6.05.10", false, "", "R6.05.10"},
+	}
+	for _, tt := range tests {
+		c := row.AddCell()
+		setCell(c, tt.col, tt.in)
+		if numeric := c.Type() == xlsx.CellTypeNumeric; numeric != tt.numeric {
+			t.Errorf("%s %q: numeric = %v, want %v", tt.col.Name, tt.in, numeric, tt.numeric)
+		}
+		if tt.numeric && c.GetNumberFormat() != tt.format {
+			t.Errorf("%s %q: format = %q, want %q", tt.col.Name, tt.in, c.GetNumberFormat(), tt.format)
+		}
+		if c.Value != tt.want {
+			t.Errorf("%s %q: value = %q, want %q", tt.col.Name, tt.in, c.Value, tt.want)
+		}
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	for decimals, want := range map[int]string{0: "0", 1: "0.0", 3: "0.000", -1: "0"} {
+		if got := numberFormat(decimals); got != want {
+			t.Errorf("numberFormat(%d) = %q, want %q", decimals, got, want)
+		}
+	}
+}
+
+func TestFormatRow(t *testing.T) {
+	cols := []Column{textCol("証番号"), numberCol("身長", 1), numberCol("血圧（収縮期）", 0), numberCol("HｂA1ｃ", 1), dateCol("受診日")}
+	got := formatRow(cols, []string{"0012", "170", "120.0", "", "2024/05/10"})
+	want := []string{"0012", "170.0", "120", "", "2024/05/10"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("formatRow = %q, want %q", got, want)
+	}
+}
+
+func TestProfileLayouts(t *testing.T) {
+	resetState(t)
+	conf.Layouts.Profiles = map[string][]string{
+		"がん検診": {"gastric", "uterine", "breast", "prostate", "mmg", "colorectal", "lung"},
+	}
+
+	// 設定で出力しない大腸がん・肺がんは除き、乳がんをまとめる設定ならマンモも除く
+	tests := []struct {
+		enabled, combined bool
+		want              string
+	}{
+		{false, false, "gastric,uterine,breast,prostate,mmg"},
+		{true, false, "gastric,uterine,breast,prostate,mmg,colorectal,lung"},
+		{false, true, "gastric,uterine,breast,prostate"},
+	}
+	for _, tt := range tests {
+		conf.Colorectal.Enabled, conf.Lung.Enabled, conf.Breast.Combined = tt.enabled, tt.enabled, tt.combined
+		ls, err := chooseLayouts("", "がん検診")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := layoutKeysOf(ls); got != tt.want {
+			t.Errorf("enabled %v combined %v: layouts = %s, want %s", tt.enabled, tt.combined, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLayoutKeepEmpty(t *testing.T) {
+	resetState(t)
+	inRecs := testInput(testRec("101", nil)) // 胃がん検診を受けていない
+
+	for _, keep := range []bool{false, true} {
+		conf.Layouts.KeepEmpty = keep
+		dir := t.TempDir()
+		rows := writeLayout(filepath.Join(dir, "x"), gastricLayout, inRecs)
+		if len(rows) != 0 {
+			t.Fatalf("rows = %d, want 0", len(rows))
+		}
+
+		// 既定は作らない。keep_empty ならタイトル行だけのファイル
+		files, _ := filepath.Glob(filepath.Join(dir, "*.xlsx"))
+		if !keep {
+			if len(files) != 0 {
+				t.Errorf("keep_empty false: files = %v", files)
+			}
+			continue
+		}
+		if len(files) != 1 {
+			t.Fatalf("keep_empty true: files = %v", files)
+		}
+		f, err := xlsx.OpenFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(f.Sheets[0].Rows); n != 1 {
+			t.Errorf("keep_empty true: rows = %d, want header only", n)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ログのレベル
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// 既定のログファイル名
+const logName = "log.txt"
+
+// logEntry はログ１件
+type logEntry struct {
+	at     time.Time
+	level  logLevel
+	msg    string
+	fields []logField // 追加した順に出力する（JSON でも同じ順）
+}
+
+// logField はログの項目１つ
+type logField struct {
+	key   string
+	value interface{}
+}
+
+// rowContext は変換中の抽出データの行（警告に行・受診者をつける）
+type rowContext struct {
+	layout string
+	line   int
+	key    string
+	name   string
+}
+
+// runLogger は実行のログ
+// ログファイルの場所は出力先が決まるまで分からないため、開くまでは溜めておく
+type runLogger struct {
+	file    *os.File
+	pending []logEntry
+	row     *rowContext
+}
+
+var runLog = &runLogger{}
+
+// runID は実行ごとのID（実行日時とプロセス番号）
+var runID = fmt.Sprintf("%s-%04x", runTime.Format("20060102-150405"), os.Getpid()&0xffff)
+
+func logDebug(msg string, kv ...interface{}) { runLog.add(levelDebug, msg, kv) }
+func logInfo(msg string, kv ...interface{})  { runLog.add(levelInfo, msg, kv) }
+func logWarn(msg string, kv ...interface{})  { runLog.add(levelWarn, msg, kv) }
+func logError(msg string, kv ...interface{}) { runLog.add(levelError, msg, kv) }
+
+// add はログを１件追加する。変換中の行があれば検診・行・記号-番号・氏名をつける
+func (l *runLogger) add(level logLevel, msg string, kv []interface{}) {
+	var fields []logField
+	if r := l.row; r != nil && level >= levelWarn {
+		fields = []logField{{"layout", r.layout}, {"line", r.line}, {"key", r.key}, {"name", r.name}}
+	}
+	for I := 0; I+1 < len(kv); I += 2 {
+		v := kv[I+1]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields = setField(fields, fmt.Sprint(kv[I]), v)
+	}
+
+	e := logEntry{time.Now(), level, msg, fields}
+	if l.file == nil {
+		l.pending = append(l.pending, e)
+		return
+	}
+	l.write(e)
+}
+
+// setField は項目を追加する（同じ項目名があれば値を置き換える）
+func setField(fields []logField, k string, v interface{}) []logField {
+	for I := range fields {
+		if fields[I].key == k {
+			fields[I].value = v
+			return fields
+		}
+	}
+	return append(fields, logField{k, v})
+}
+
+// setRow は変換中の行を設定する（nil で解除）
+func setRow(r *rowContext) {
+	runLog.row = r
+}
+
+// openLog はログファイルを開き、溜めておいたログを書き出す
+// 設定の log.path があればそこに、無ければ dir（出力フォルダ・exe のフォルダ）の log.txt に書く
+// 大きくなったログは log.1.txt・log.2.txt … に切り替える
+func openLog(dir string) {
+	if runLog.file != nil {
+		return
+	}
+
+	path := conf.Log.Path
+	if path == "" {
+		path = filepath.Join(dir, logName)
+	}
+	rotateLog(path)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		// 出力先に書けない場合は exe と同じフォルダにする
+		path = filepath.Join(filepath.Dir(configPath()), logName)
+		file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ログファイルを開けません", err)
+			return
+		}
+	}
+	runLog.file = file
+
+	for _, e := range runLog.pending {
+		runLog.write(e)
+	}
+	runLog.pending = nil
+}
+
+// closeLog はログファイルを閉じる
+// ログファイルを開いていなければ（変換しないサブコマンドなど） exe と同じフォルダに書く
+func closeLog() {
+	openLog(filepath.Dir(configPath()))
+	if runLog.file != nil {
+		runLog.file.Close()
+		runLog.file = nil
+	}
+}
+
+// exit はログを閉じて終了コードで終わる
+func exit(code int) {
+	logInfo("Finish", "code", code)
+	closeLog()
+	os.Exit(code)
+}
+
+// rotateLog はログファイルが設定の大きさ（KB）を超えていたら古いログに切り替える
+// log.txt → log.1.txt → log.2.txt … と送り、設定の数より古いものは消す
+func rotateLog(path string) {
+	fi, err := os.Stat(path)
+	if err != nil || conf.Log.MaxSize <= 0 || fi.Size() < int64(conf.Log.MaxSize)*1024 {
+		return
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	old := func(n int) string {
+		return base + "." + strconv.Itoa(n) + ext
+	}
+
+	os.Remove(old(conf.Log.Keep))
+	for n := conf.Log.Keep - 1; n >= 1; n-- {
+		os.Rename(old(n), old(n+1))
+	}
+	if conf.Log.Keep > 0 {
+		os.Rename(path, old(1))
+	} else {
+		os.Remove(path)
+	}
+}
+
+// write はログ１件を設定の形式（text・json）で書く。設定のレベルより低いものは書かない
+func (l *runLogger) write(e logEntry) {
+	if e.level < parseLevel(conf.Log.Level) {
+		return
+	}
+
+	var line string
+	if conf.Log.Format == "json" {
+		var b strings.Builder
+		b.WriteString("{")
+		fields := append([]logField{
+			{"time", e.at.Format("2006-01-02T15:04:05.000Z07:00")},
+			{"level", levelNames[e.level]},
+			{"run", runID},
+			{"msg", e.msg},
+		}, e.fields...)
+		for I, f := range fields {
+			if I > 0 {
+				b.WriteString(",")
+			}
+			k, _ := json.Marshal(f.key)
+			v, err := json.Marshal(f.value)
+			if err != nil {
+				v, _ = json.Marshal(fmt.Sprint(f.value))
+			}
+			b.Write(k)
+			b.WriteString(":")
+			b.Write(v)
+		}
+		b.WriteString("}")
+		line = b.String() + "\n"
+	} else {
+		line = fmt.Sprintf("%s %-5s %s %s", e.at.Format("2006/01/02 15:04:05"), levelNames[e.level], runID, e.msg)
+		for _, f := range e.fields {
+			line += " " + f.key + "=" + quoteValue(fmt.Sprint(f.value))
+		}
+		line += "\r\n"
+	}
+
+	if _, err := l.file.WriteString(line); err != nil {
+		fmt.Fprintln(os.Stderr, "ログを書けません", err)
+	}
+}
+
+// quoteValue は空白を含む値・空欄を " で囲む（text 形式）
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// parseLevel は設定のレベルの名前（debug・info・warn・error）を返す。不明なら info
+func parseLevel(s string) logLevel {
+	for I, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return logLevel(I)
+		}
+	}
+	if strings.EqualFold(s, "warning") {
+		return levelWarn
+	}
+	return levelInfo
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testLog は新しいログに切り替え、テストの終わりに元に戻す
+func testLog(t *testing.T) {
+	t.Helper()
+	resetState(t)
+	saved := runLog
+	runLog = &runLogger{}
+	t.Cleanup(func() { runLog = saved })
+}
+
+// readLog はログファイルの行を返す
+func readLog(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimRight(string(b), "\r\n"), "\n")
+}
+
+func TestLogLevelPending(t *testing.T) {
+	testLog(t)
+	conf.Log.Level = "warn"
+
+	// ログファイルを開く前のログも溜めておいて書き出す
+	logInfo("開く前 info")
+	logWarn("開く前 warn", "value", "a b")
+	dir := t.TempDir()
+	openLog(dir)
+	logDebug("開いた後 debug")
+	logError("開いた後 error")
+	closeLog()
+
+	lines := readLog(t, filepath.Join(dir, logName))
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want warn and error only", lines)
+	}
+	if !strings.Contains(lines[0], "WARN  "+runID+" 開く前 warn value=\"a b\"") {
+		t.Errorf("lines[0] = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "ERROR "+runID+" 開いた後 error") {
+		t.Errorf("lines[1] = %q", lines[1])
+	}
+}
+
+func TestLogJSON(t *testing.T) {
+	testLog(t)
+	conf.Log.Format = "json"
+
+	dir := t.TempDir()
+	openLog(dir)
+	setRow(&rowContext{"健診", 3, "3025-101", "ｹﾝﾎﾟ ﾀﾛｳ"})
+	logWarn("性別エラー", "value", "不明\n改行", "line", 5)
+	setRow(nil)
+	logInfo("Finish", "code", 0)
+	closeLog()
+
+	// １件１行で、項目は追加した順（同じ項目名は置き換え）
+	lines := readLog(t, filepath.Join(dir, logName))
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want 2", lines)
+	}
+	var e map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("json: %v: %s", err, lines[0])
+	}
+	if e["level"] != "WARN" || e["run"] != runID || e["msg"] != "性別エラー" || e["layout"] != "健診" || e["line"] != 5.0 || e["value"] != "不明\n改行" {
+		t.Errorf("entry = %v", e)
+	}
+	order := []string{`"time"`, `"level"`, `"run"`, `"msg"`, `"layout"`, `"line"`, `"key"`, `"name"`, `"value"`}
+	for I := 1; I < len(order); I++ {
+		if strings.Index(lines[0], order[I-1]) > strings.Index(lines[0], order[I]) {
+			t.Errorf("%s is before %s: %s", order[I], order[I-1], lines[0])
+		}
+	}
+	if strings.Contains(lines[1], `"layout"`) {
+		t.Errorf("row fields after setRow(nil): %s", lines[1])
+	}
+}
+
+func TestRotateLog(t *testing.T) {
+	resetState(t)
+	conf.Log.MaxSize, conf.Log.Keep = 1, 2
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, logName)
+	write := func(name string, s string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(s), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	// 大きさが MaxSize 未満なら切り替えない
+	write(logName, "small")
+	rotateLog(path)
+	if read(logName) != "small" {
+		t.Fatalf("rotated a small log")
+	}
+
+	// log.txt → log.1.txt → log.2.txt と送り、Keep より古いものは消す
+	big := strings.Repeat("x", 1024)
+	write(logName, big)
+	write("log.1.txt", "one")
+	write("log.2.txt", "two")
+	rotateLog(path)
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("log.txt is left: %v", err)
+	}
+	if read("log.1.txt") != big || read("log.2.txt") != "one" {
+		t.Errorf("log.1.txt = %d bytes, log.2.txt = %q", len(read("log.1.txt")), read("log.2.txt"))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "log.3.txt")); !os.IsNotExist(err) {
+		t.Errorf("log.3.txt exists with keep 2")
+	}
+}
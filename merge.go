@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mergeConflict は統合時に受診日ごとに値が食い違った項目
+type mergeConflict struct {
+	Key    string   // 事業所記号-証番号 カナ氏名
+	Column string   // 項目名（入力ファイルの見出し）
+	Values []string // 受診日順の値
+}
+
+// 入力ファイルの検査結果の列（これより前は受診者・受診日の列）
+const mergeResultFrom = 10
+
+// mergeVisits は同じ人の年度内の分割受診（採血とX線が別日など）を１行にまとめる
+// 先頭行（見出し）はそのまま残す
+// 検査結果の列で日によって値が違うものはチェックの一覧に出す
+func mergeVisits(inRecs [][]string, jushinbi string) ([][]string, []mergeConflict) {
+	if len(inRecs) == 0 {
+		return inRecs, nil
+	}
+
+	header := inRecs[0]
+	outRecs := [][]string{header}
+	var conflicts []mergeConflict
+
+	// 同一人物・同一年度ごとにまとめる（入力順を保つ）
+	groups := make(map[string][]int)
+	var keys []string
+	for J := 1; J < len(inRecs); J++ {
+		//　保険証番号が空欄は、データ出力対象外なので統合しない
+		if inRecs[J][6] == "" {
+			outRecs = append(outRecs, inRecs[J])
+			continue
+		}
+		// 受診日が日付でなければ年度が分からないため統合しない（別の年度の受診をまとめないように）
+		if nendo(inRecs[J][4]) == 0 {
+			addCheck("入力", inRecs[J], "受診日", inRecs[J][4], "受診日が日付でないため複数日受診を統合しません")
+			outRecs = append(outRecs, inRecs[J])
+			continue
+		}
+
+		key := visitKey(inRecs[J])
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], J)
+	}
+
+	for _, key := range keys {
+		rows := groups[key]
+		if len(rows) == 1 {
+			outRecs = append(outRecs, inRecs[rows[0]])
+			continue
+		}
+
+		// 受診日順に並べる
+		sort.SliceStable(rows, func(a, b int) bool {
+			return inRecs[rows[a]][4] < inRecs[rows[b]][4]
+		})
+
+		mainRow := mainVisit(inRecs, rows, jushinbi)
+		merged := make([]string, len(header))
+		copy(merged, inRecs[mainRow])
+
+		for I := range header {
+			if I == 4 {
+				continue // 受診日は設定で選んだ日
+			}
+
+			var values []string
+			for _, r := range rows {
+				v := inRecs[r][I]
+				if v == "" {
+					continue
+				}
+				if merged[I] == "" {
+					merged[I] = v
+				}
+				if !contains(values, v) {
+					values = append(values, v)
+				}
+			}
+
+			if len(values) > 1 && I >= mergeResultFrom {
+				c := mergeConflict{
+					Key:    inRecs[mainRow][5] + "-" + inRecs[mainRow][6] + " " + inRecs[mainRow][7],
+					Column: header[I],
+					Values: values,
+				}
+				conflicts = append(conflicts, c)
+				addCheckKey("入力", inRecs[mainRow][5]+"-"+inRecs[mainRow][6], inRecs[mainRow][7], c.Column, strings.Join(c.Values, ","), "複数日受診で値が違う（"+merged[I]+" を使用）")
+			}
+		}
+
+		for k := 1; k < len(rows); k++ {
+			addExclusion("入力", "複数日受診を統合")
+		}
+		logInfo("受診日統合", "key", inRecs[mainRow][5]+"-"+inRecs[mainRow][6], "name", inRecs[mainRow][7], "visits", len(rows), "jushinbi", merged[4])
+		outRecs = append(outRecs, merged)
+	}
+
+	return outRecs, conflicts
+}
+
+// visitKey は統合の単位（事業所記号・証番号・氏名・生年月日・年度）
+func visitKey(rec []string) string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%d", rec[5], rec[6], rec[7], rec[9], nendo(rec[4]))
+}
+
+// nendo は受診日（yyyy-mm-dd）の年度を返す。日付でなければ 0
+func nendo(s string) int {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t, err = time.Parse("2006/01/02", s)
+		if err != nil {
+			return 0
+		}
+	}
+
+	if t.Month() < time.April {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// mainVisit は受診日として採用する行を返す（rows は受診日順）
+func mainVisit(inRecs [][]string, rows []int, jushinbi string) int {
+	switch jushinbi {
+	case "last":
+		return rows[len(rows)-1]
+	case "main":
+		// 入力された項目が一番多い日を主受診日とする
+		m := rows[0]
+		most := -1
+		for _, r := range rows {
+			n := 0
+			for _, v := range inRecs[r] {
+				if v != "" {
+					n++
+				}
+			}
+			if n > most {
+				most = n
+				m = r
+			}
+		}
+		return m
+	case "first":
+		return rows[0]
+	default:
+		logWarn("受診日の選択設定エラー", "value", jushinbi)
+		return rows[0]
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestNendo(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"2024-04-01", 2024},
+		{"2025-03-31", 2024},
+		{"2024/12/10", 2024},
+		{"2025/01/05", 2024},
+		{"", 0},
+		{"R6.04.01", 0},
+	}
+	for _, tt := range tests {
+		if got := nendo(tt.in); got != tt.want {
+			t.Errorf("nendo(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMergeVisits(t *testing.T) {
+	resetState(t)
+
+	row := func(day string, kubun string, shincho string, taiju string) []string {
+		return testRec("101", map[int]string{3: kubun, 4: day, 10: shincho, 11: taiju})
+	}
+	inRecs := testInput(
+		row("2024-06-01", "本人", "170", ""),
+		row("2024-05-10", "", "171", "60"),
+		row("2025-05-10", "本人", "172", "61"), // 次の年度は別の受診
+	)
+	inRecs[0][3], inRecs[0][10], inRecs[0][11] = "区分", "身長", "体重"
+
+	out, conflicts := mergeVisits(inRecs, "first")
+	if len(out) != 3 {
+		t.Fatalf("mergeVisits rows = %d, want 3", len(out))
+	}
+
+	m := out[1]
+	if m[4] != "2024-05-10" || m[3] != "本人" || m[10] != "171" || m[11] != "60" {
+		t.Errorf("merged = %v", m)
+	}
+
+	// 受診者の列（区分）は値の違いに数えず、検査結果（身長）だけ
+	if len(conflicts) != 1 || conflicts[0].Column != "身長" {
+		t.Errorf("conflicts = %v, want 身長 only", conflicts)
+	}
+	if len(checks) != 1 || checks[0].Field != "身長" || checks[0].Value != "171,170" {
+		t.Errorf("checks = %v, want 身長 171,170", checks)
+	}
+}
+
+func TestMainVisit(t *testing.T) {
+	inRecs := [][]string{
+		nil,
+		{"2024-05-10", "1", ""},
+		{"2024-06-01", "1", "2"},
+		{"2024-06-15", "", ""},
+	}
+	rows := []int{1, 2, 3}
+	for jushinbi, want := range map[string]int{"first": 1, "last": 3, "main": 2} {
+		if got := mainVisit(inRecs, rows, jushinbi); got != want {
+			t.Errorf("mainVisit(%s) = %d, want %d", jushinbi, got, want)
+		}
+	}
+}
+
+func TestMergeVisitsBadDate(t *testing.T) {
+	resetState(t)
+
+	row := func(day string, v string) []string {
+		return testRec("101", map[int]string{4: day, 11: v})
+	}
+	inRecs := testInput(row("R6.05.10", "1"), row("R6.06.01", "2"), row("2024-06-15", "3"))
+
+	out, conflicts := mergeVisits(inRecs, "first")
+
+	// 受診日が日付でない行はまとめずにそのまま残し、チェック結果に出す
+	if len(out) != 4 || len(conflicts) != 0 || len(exclusions) != 0 {
+		t.Errorf("mergeVisits rows = %d, conflicts = %v, exclusions = %v", len(out), conflicts, exclusions)
+	}
+	if len(checks) != 2 || checks[0].Field != "受診日" || checks[0].Value != "R6.05.10" {
+		t.Errorf("checks = %v, want 2 受診日", checks)
+	}
+}
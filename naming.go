@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runTime は実行を始めた日時
+// 日付をまたいで実行しても、出力フォルダ・ファイル名の日付はすべてこの日時にする
+var runTime = time.Now()
+
+// outputs は実行中に作った出力フォルダ・ファイル（最後に画面に表示する）
+var outputs []string
+
+// expandName は名前のひな形の {name} {date} {time} {input} を置き換える
+func expandName(tmpl string, name string, input string) string {
+	r := strings.NewReplacer(
+		"{name}", name,
+		"{date}", runTime.Format("20060102"),
+		"{time}", runTime.Format("150405"),
+		"{input}", input,
+	)
+	return r.Replace(tmpl)
+}
+
+// fileTemplate は出力ファイルの名前のひな形（空欄なら既定）
+func fileTemplate() string {
+	if conf.Naming.File == "" {
+		return defaultConfig().Naming.File
+	}
+	return conf.Naming.File
+}
+
+// dirCreate は抽出データの出力フォルダを作る（区切り文字で終わる名前を返す）
+// 出力先は -out（設定の naming.out）、無ければ抽出データと同じフォルダ
+// each は抽出データごとに変換する場合で、名前に {input} が無ければ _ファイル名 をつける
+func dirCreate(path string, each bool) string {
+	dir := conf.Naming.Out
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+
+	tmpl := conf.Naming.Folder
+	if each && !strings.Contains(tmpl, "{input}") {
+		tmpl += "_{input}"
+	}
+
+	outDir := dir
+	if name := expandName(tmpl, "", baseName(path)); name != "" {
+		outDir = availablePath(filepath.Join(dir, name))
+	}
+
+	err := os.MkdirAll(outDir, 0777)
+	failOnError(err)
+	logInfo("出力フォルダ", "path", outDir)
+	outputs = append(outputs, outDir)
+
+	return outDir + string(filepath.Separator)
+}
+
+// outputFile は出力フォルダに作るファイルの名前を返す
+// name は 健診データ・チェック結果 などの出力の名前、ext は拡張子
+func outputFile(dir string, name string, ext string) string {
+	path := availablePath(filepath.Join(dir, expandName(fileTemplate(), name, "")) + ext)
+	outputs = append(outputs, path)
+	return path
+}
+
+// availablePath は同じ名前のフォルダ・ファイルがある場合に上書きの設定に従った名前を返す
+// fail:中止する suffix:_2・_3 をつける replace:そのまま（上書きする）
+func availablePath(path string) string {
+	p, err := nextPath(path)
+	if err != nil {
+		notice(err.Error())
+		exit(1)
+	}
+	return p
+}
+
+// nextPath は上書きの設定に従った名前を返す。fail で同じ名前がある場合はエラー
+func nextPath(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return path, nil
+	}
+
+	switch conf.Naming.Overwrite {
+	case "replace":
+		logInfo("上書きします", "path", path)
+		return path, nil
+	case "fail":
+		return "", fmt.Errorf("同じ名前の出力があるため中止します %s", path)
+	}
+
+	ext := filepath.Ext(path)
+	if isDir(path) {
+		ext = ""
+	}
+	base := strings.TrimSuffix(path, ext)
+	for I := 2; ; I++ {
+		p := base + "_" + strconv.Itoa(I) + ext
+		if _, err := os.Stat(p); err != nil {
+			return p, nil
+		}
+	}
+}
+
+// printOutputs は作った出力フォルダ・ファイルを画面に表示する
+func printOutputs() {
+	if len(outputs) == 0 {
+		return
+	}
+	fmt.Println("出力:")
+	for _, path := range outputs {
+		fmt.Println("  " + path)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNextPath(t *testing.T) {
+	resetState(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.xlsx", "b.xlsx", "b_2.xlsx", "d"} {
+		path := filepath.Join(dir, name)
+		var err error
+		if filepath.Ext(name) == "" {
+			err = os.Mkdir(path, 0777)
+		} else {
+			err = os.WriteFile(path, nil, 0666)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		overwrite string
+		name      string
+		want      string // 空欄ならエラー
+	}{
+		{"suffix", "new.xlsx", "new.xlsx"},
+		{"suffix", "a.xlsx", "a_2.xlsx"},
+		{"suffix", "b.xlsx", "b_3.xlsx"}, // _2 もあれば _3
+		{"suffix", "d", "d_2"},           // フォルダは拡張子を分けない
+		{"", "a.xlsx", "a_2.xlsx"},       // 空欄は suffix と同じ
+		{"replace", "a.xlsx", "a.xlsx"},
+		{"fail", "new.xlsx", "new.xlsx"},
+		{"fail", "a.xlsx", ""},
+	}
+	for _, tt := range tests {
+		conf.Naming.Overwrite = tt.overwrite
+		got, err := nextPath(filepath.Join(dir, tt.name))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s %s: nextPath = %q, want error", tt.overwrite, tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != filepath.Join(dir, tt.want) {
+			t.Errorf("%s %s: nextPath = %q, %v, want %s", tt.overwrite, tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandName(t *testing.T) {
+	date, tm := runTime.Format("20060102"), runTime.Format("150405")
+	tests := []struct {
+		tmpl, name, input string
+		want              string
+	}{
+		{"松英会職員{name}{date}", "健診データ", "", "松英会職員健診データ" + date},
+		{"{date}_{time}", "", "", date + "_" + tm},
+		{"松英会職員健診データ{date}_{input}", "", "4月分", "松英会職員健診データ" + date + "_4月分"},
+		{"{input}{input}", "", "a", "aa"},
+		{"固定の名前", "健診データ", "a", "固定の名前"},
+		{"", "健診データ", "a", ""},
+	}
+	for _, tt := range tests {
+		if got := expandName(tt.tmpl, tt.name, tt.input); got != tt.want {
+			t.Errorf("expandName(%q, %q, %q) = %q, want %q", tt.tmpl, tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDirCreate(t *testing.T) {
+	resetState(t)
+	date := runTime.Format("20060102")
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		folder string
+		each   bool
+		exists string // 先に作っておくフォルダ
+		want   string
+	}{
+		{"松英会職員健診データ{date}", false, "", "松英会職員健診データ" + date},
+		{"松英会職員健診データ{date}", false, "松英会職員健診データ" + date, "松英会職員健診データ" + date + "_2"},
+		{"松英会職員健診データ{date}", true, "", "松英会職員健診データ" + date + "_4月分"},
+		{"{input}_{date}", true, "", "4月分_" + date}, // {input} があれば _ファイル名 をつけない
+		{"", false, "", ""}, // 出力先に直接出力する
+	}
+	for _, tt := range tests {
+		out := t.TempDir()
+		if tt.exists != "" {
+			if err := os.Mkdir(filepath.Join(out, tt.exists), 0777); err != nil {
+				t.Fatal(err)
+			}
+		}
+		conf.Naming.Out, conf.Naming.Folder = out, tt.folder
+
+		got := dirCreate(filepath.Join("input", "4月分.txt"), tt.each)
+		want := filepath.Join(out, tt.want) + sep
+		if got != want {
+			t.Errorf("%q each %v: dirCreate = %q, want %q", tt.folder, tt.each, got, want)
+		}
+		if !isDir(got) {
+			t.Errorf("%q each %v: %s is not created", tt.folder, tt.each, got)
+		}
+	}
+	if len(outputs) != len(tests) {
+		t.Errorf("outputs = %v", outputs)
+	}
+}
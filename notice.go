@@ -0,0 +1,255 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+)
+
+// 個人結果通知のひな形の差し込み（{項目名}・{前回:項目名}）
+var noticeField = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// 質問票の回答（健診データのコード → 回答）。ここに無い項目は はい・いいえ
+var monshinAnswers = map[string][]string{
+	"たばこ":    tabakoNames,
+	"お酒・頻度":  sakeNames,
+	"お酒・量":   sakeryoNames,
+	"食事噛む状態": {"", "何でも", "かみにくい", "ほとんどかめない"},
+	"食べる速度":  {"", "速い", "普通", "遅い"},
+	"間食":     {"", "毎日", "時々", "ほとんど摂取しない"},
+	"改善の意思":  {"", "しない", "思う", "始めた", "６ヶ月経過", "６ヶ月以上"},
+}
+
+var yesNoNames = []string{"", "はい", "いいえ"}
+
+// 所見の有無の検査（健診データのコード 1:所見あり 2:所見なし）
+var syokenumuNames = []string{"", "所見あり", "所見なし"}
+
+var syokenumuResults = []string{"心電図所見", "眼底精密所見", "胸部X線検査判定", "腹部超音波検査判定"}
+
+// writeNotices は受診者ごとの結果通知（エクセルファイル）を出力フォルダの 結果通知\所属 に作る
+// 設定のひな形があればその差し込み、無ければ既定の様式にする
+// 履歴があれば前年度以前の最後の受診の値を前回として並べる
+func writeNotices(filename string, exams []examRecord, records [][]string) {
+	if len(exams) == 0 {
+		return
+	}
+
+	prev := map[string][]historyEntry{}
+	if conf.History.Enabled && historyExists() {
+		db := openHistory()
+		prev = loadHistory(db, "")
+		db.Close()
+	}
+
+	dir, _ := filepath.Split(filename)
+	dir = availablePath(filepath.Join(dir, "結果通知"))
+	outputs = append(outputs, dir)
+
+	shozoku := departments(records)
+	for _, r := range exams {
+		p, hasPrev := previousExam(prev[personKey(r)], r)
+		values := noticeValues(r, shozoku[personKey(r)])
+		if hasPrev {
+			for k, v := range noticeValues(p, shozoku[personKey(r)]) {
+				values["前回:"+k] = v
+			}
+		}
+
+		var excelFile *xlsx.File
+		if conf.Notice.Template != "" {
+			excelFile = fillTemplate(values)
+		} else {
+			excelFile = defaultNotice(r, p, hasPrev, values)
+		}
+
+		dept := shozoku[personKey(r)]
+		if dept == "" {
+			dept = "所属なし"
+		}
+		deptDir := filepath.Join(dir, safeName(dept))
+		err := os.MkdirAll(deptDir, 0777)
+		failOnError(err)
+
+		name := expandName(fileTemplate(), "結果通知_"+personKey(r)+"_"+safeName(r.Kana), "")
+		err = excelFile.Save(availablePath(filepath.Join(deptDir, name) + ".xlsx"))
+		failOnError(err)
+	}
+
+	logInfo("結果通知", "count", len(exams), "path", dir)
+}
+
+// previousExam は受診者の履歴から今回の年度より前の最後の健診結果を返す
+func previousExam(entries []historyEntry, r examRecord) (examRecord, bool) {
+	y := nendo(r.Jushinbi)
+	for I := len(entries) - 1; I >= 0; I-- {
+		if nendo(entries[I].Record.Jushinbi) < y {
+			return entries[I].Record, true
+		}
+	}
+	return examRecord{}, false
+}
+
+// noticeValues は結果通知に差し込む値（項目名 → 値）を返す
+func noticeValues(r examRecord, shozoku string) map[string]string {
+	v := map[string]string{
+		"受診日":    strings.Replace(r.Jushinbi, "-", "/", -1),
+		"記号-番号":  personKey(r),
+		"事業所記号":  r.Kigo,
+		"証番号":    r.Bango,
+		"カナ氏名":   r.Kana,
+		"性別":     seiName(r.Sei),
+		"生年月日":   strings.Replace(r.Birth, "-", "/", -1),
+		"所属":     shozoku,
+		"総合判定":   r.Sogo,
+		"医師の診断":  r.Shindan,
+		"医師名":    r.Ishi,
+		"自覚症状所見": r.Jikaku,
+		"他覚症状所見": r.Takaku,
+	}
+	if a, ok := age(strings.Replace(r.Birth, "-", "/", -1), r.Jushinbi); ok {
+		v["年齢"] = strconv.Itoa(a)
+	}
+
+	var kiou []string
+	for _, k := range r.Kiou {
+		kiou = append(kiou, k.Name)
+	}
+	v["既往歴"] = strings.Join(kiou, "、")
+
+	for _, res := range r.Results {
+		v[res.Name] = resultText(res)
+		if contains(syokenumuResults, res.Name) {
+			v[res.Name] = codeName(syokenumuNames, res.Text)
+		}
+	}
+	for name, code := range r.Monshin {
+		v[name] = monshinAnswer(name, code)
+	}
+	for _, c := range r.Cancers {
+		v[c.Name] = cancerRank(c.Result)
+		v[c.Name+"・所見"] = c.Findings
+	}
+	return v
+}
+
+// monshinAnswer は質問票のコードを回答の文字にする
+func monshinAnswer(name string, code string) string {
+	answers, ok := monshinAnswers[name]
+	if !ok {
+		answers = yesNoNames
+	}
+	return codeName(answers, code)
+}
+
+// codeName は健診データのコード（1から）を名前にする（一覧に無いコードはそのまま）
+func codeName(names []string, code string) string {
+	if I, err := strconv.Atoi(code); err == nil && I > 0 && I < len(names) {
+		return names[I]
+	}
+	return code
+}
+
+// fillTemplate はひな形の {項目名} を受診者の値に置き換える（無い項目は空欄）
+// セルが差し込み１つだけで値が数値なら数値のセルにする
+func fillTemplate(values map[string]string) *xlsx.File {
+	excelFile, err := xlsx.OpenFile(conf.Notice.Template)
+	failOnError(err)
+
+	for _, sheet := range excelFile.Sheets {
+		for _, row := range sheet.Rows {
+			for _, cell := range row.Cells {
+				if !strings.Contains(cell.Value, "{") {
+					continue
+				}
+				if m := noticeField.FindStringSubmatch(cell.Value); m != nil && m[0] == cell.Value {
+					if f, err := strconv.ParseFloat(values[m[1]], 64); err == nil {
+						cell.SetFloat(f)
+						continue
+					}
+				}
+				cell.Value = noticeField.ReplaceAllStringFunc(cell.Value, func(s string) string {
+					return values[s[1:len(s)-1]]
+				})
+			}
+		}
+	}
+	return excelFile
+}
+
+// defaultNotice はひな形が無い場合の結果通知（受診者・検査結果・判定・がん検診・質問票）
+func defaultNotice(r examRecord, p examRecord, hasPrev bool, values map[string]string) *xlsx.File {
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("結果通知")
+	failOnError(err)
+
+	addRow(sheet, []string{"健康診断結果のお知らせ"})
+	addRow(sheet, nil)
+	for _, name := range []string{"受診日", "記号-番号", "カナ氏名", "所属", "性別", "年齢"} {
+		addRow(sheet, []string{name, values[name]})
+	}
+
+	// 検査結果（健診データの列の順。前回だけの項目も出す）
+	addRow(sheet, nil)
+	previous := "前回"
+	if hasPrev {
+		previous = "前回（" + values["前回:受診日"] + "）"
+	}
+	addRow(sheet, []string{"項目", "今回", previous, "単位"})
+	cols := kenshinLayout{}.Columns()
+	for I := resultFrom; I <= resultTo; I++ {
+		name := cols[I].Name
+		cur, okCur := values[name]
+		old, okOld := values["前回:"+name]
+		if !okCur && !okOld {
+			continue
+		}
+		unit := ""
+		if l, ok := labItem(name); ok {
+			unit = l.Unit
+		}
+		addStatRow(sheet, []string{name, cur, old, unit})
+	}
+
+	addRow(sheet, nil)
+	for _, name := range []string{"総合判定", "医師の診断", "医師名", "自覚症状所見", "他覚症状所見", "既往歴"} {
+		addRow(sheet, []string{name, values[name], values["前回:"+name]})
+	}
+
+	if len(r.Cancers) > 0 || len(p.Cancers) > 0 {
+		addRow(sheet, nil)
+		addRow(sheet, []string{"がん検診", "結果", "所見", previous})
+		for _, l := range layouts() {
+			name := l.Name()
+			if _, ok := l.(cancerLayout); !ok {
+				continue
+			}
+			if _, ok := values[name]; !ok && values["前回:"+name] == "" {
+				continue
+			}
+			addRow(sheet, []string{name, values[name], values[name+"・所見"], values["前回:"+name]})
+		}
+	}
+
+	if len(r.Monshin) > 0 {
+		addRow(sheet, nil)
+		addRow(sheet, []string{"質問票", "回答"})
+		for I := monshinFrom; I <= monshinTo; I++ {
+			if v, ok := values[cols[I].Name]; ok {
+				addRow(sheet, []string{cols[I].Name, v})
+			}
+		}
+	}
+
+	return excelFile
+}
+
+// safeName はフォルダ・ファイルの名前に使えない文字を _ にする
+func safeName(s string) string {
+	return strings.NewReplacer(`\`, "_", "/", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_").Replace(s)
+}
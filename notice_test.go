@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestNoticeValues(t *testing.T) {
+	r := examRecord{
+		Jushinbi: "2024-05-10",
+		Kigo:     "3025",
+		Bango:    "101",
+		Sei:      "2",
+		Birth:    "1990-06-01",
+		Results: []examResult{
+			{Name: "胸部X線検査判定", Text: "1"},
+			{Name: "心電図所見", Text: "2"},
+			{Name: "尿糖", Text: "-"},
+		},
+		Monshin: map[string]string{"たばこ": "3", "服薬・血圧": "1"},
+		Cancers: []examCancer{
+			{Name: "前立腺がん検診", Result: "4"},
+			{Name: "大腸がん検診", Result: "4", Findings: "便潜血 1日目- 2日目+"},
+			{Name: "乳がん検診", Result: "6"},
+		},
+	}
+
+	v := noticeValues(r, "本部")
+	want := map[string]string{
+		"受診日":       "2024/05/10",
+		"記号-番号":     "3025-101",
+		"性別":        "女",
+		"年齢":        "33",
+		"所属":        "本部",
+		"胸部X線検査判定":  "所見あり",
+		"心電図所見":     "所見なし",
+		"尿糖":        "-",
+		"たばこ":       "いいえ",
+		"服薬・血圧":     "はい",
+		"前立腺がん検診":   "要再検",
+		"大腸がん検診":    "要再検",
+		"大腸がん検診・所見": "便潜血 1日目- 2日目+",
+		"乳がん検診":     "治療中",
+	}
+	for k, w := range want {
+		if v[k] != w {
+			t.Errorf("noticeValues[%q] = %q, want %q", k, v[k], w)
+		}
+	}
+}
+
+func TestPreviousExam(t *testing.T) {
+	entries := []historyEntry{
+		{Record: examRecord{Jushinbi: "2022-06-01"}},
+		{Record: examRecord{Jushinbi: "2023-05-10"}},
+		{Record: examRecord{Jushinbi: "2024-05-10"}},
+	}
+	p, ok := previousExam(entries, examRecord{Jushinbi: "2024-06-15"})
+	if !ok || p.Jushinbi != "2023-05-10" {
+		t.Errorf("previousExam = %v %v, want 2023-05-10", p.Jushinbi, ok)
+	}
+	if _, ok := previousExam(entries[:1], examRecord{Jushinbi: "2022-07-01"}); ok {
+		t.Errorf("previousExam found an exam in the same year")
+	}
+}
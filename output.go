@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tealeg/xlsx"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// tableWriter はレイアウトの出力ファイル１つ分の書き出し
+type tableWriter interface {
+	Header(cols []Column)             // タイトル行
+	Row(cols []Column, cRec []string) // データ行
+	Close()                           // ファイルに保存する
+}
+
+// newTableWriter は出力設定の形式の書き出しを作る
+// dir は出力フォルダ、name は 健診データ などの出力の名前
+func newTableWriter(dir string, name string, oc OutputConfig) tableWriter {
+	switch oc.Format {
+	case "csv":
+		return newTextWriter(outputFile(dir, name, ".csv"), oc)
+	case "fixed":
+		w := &fixedWriter{text: newTextWriter(outputFile(dir, name, ".txt"), oc), widths: oc.Widths, header: oc.Header}
+		w.layout = strings.TrimSuffix(name, "データ")
+		return w
+	case "", "xlsx":
+	default:
+		logWarn("出力形式が不明なため xlsx で出力します", "value", oc.Format)
+	}
+	return newXlsxWriter(outputFile(dir, name, ".xlsx"))
+}
+
+// outputConfig はレイアウトの出力設定を返す（key は gastric などの検診の名前）
+// 全レイアウト共通（*）の設定に、レイアウトごとの設定で書いた項目を上書きする
+func outputConfig(key string) OutputConfig {
+	oc := conf.Output["*"]
+	if l, ok := conf.Output[key]; ok {
+		oc = mergeOutput(oc, l)
+	}
+	return oc
+}
+
+// mergeOutput は base に over で空欄でない項目を上書きする
+func mergeOutput(base OutputConfig, over OutputConfig) OutputConfig {
+	if over.Format != "" {
+		base.Format = over.Format
+	}
+	if over.Encoding != "" {
+		base.Encoding = over.Encoding
+	}
+	if over.Quote != "" {
+		base.Quote = over.Quote
+	}
+	if over.Newline != "" {
+		base.Newline = over.Newline
+	}
+	if len(over.Widths) > 0 {
+		base.Widths = over.Widths
+	}
+	if over.Header {
+		base.Header = true
+	}
+	return base
+}
+
+// applyOutputFlag はコマンドラインの指定（csv または kenshin=csv,gastric=fixed）を出力設定に反映する
+func applyOutputFlag(out map[string]OutputConfig, arg string, set func(oc *OutputConfig, v string)) map[string]OutputConfig {
+	if arg == "" {
+		return out
+	}
+	if out == nil {
+		out = map[string]OutputConfig{}
+	}
+
+	for _, s := range strings.Split(arg, ",") {
+		key, v := "*", s
+		if pos := strings.Index(s, "="); pos != -1 {
+			key, v = s[:pos], s[pos+1:]
+		}
+		oc := out[key]
+		set(&oc, v)
+		out[key] = oc
+	}
+	return out
+}
+
+// xlsxWriter はエクセルファイル（シート名 データ）の書き出し
+type xlsxWriter struct {
+	name  string
+	file  *xlsx.File
+	sheet *xlsx.Sheet
+}
+
+func newXlsxWriter(name string) *xlsxWriter {
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("データ")
+	failOnError(err)
+	return &xlsxWriter{name: name, file: excelFile, sheet: sheet}
+}
+
+func (w *xlsxWriter) Header(cols []Column) {
+	addRow(w.sheet, columnNames(cols))
+}
+
+func (w *xlsxWriter) Row(cols []Column, cRec []string) {
+	addTypedRow(w.sheet, cols, cRec)
+}
+
+// AddSheet はデータのシートの後にシートを追加する
+func (w *xlsxWriter) AddSheet(data sheetData) {
+	sheet, err := w.file.AddSheet(data.name)
+	failOnError(err)
+	for _, row := range data.rows {
+		addRow(sheet, row)
+	}
+}
+
+// writeSheetFile はシートを１つのエクセルファイル（松英会職員○○データ）に出力する
+func writeSheetFile(dir string, data sheetData) {
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	w := &xlsxWriter{name: outputFile(dir, data.name+"データ", ".xlsx"), file: excelFile}
+	w.AddSheet(data)
+	w.Close()
+}
+
+func (w *xlsxWriter) Close() {
+	err := w.file.Save(w.name)
+	failOnError(err)
+}
+
+// textWriter は文字コード・改行を指定したテキストファイル（CSV）の書き出し
+type textWriter struct {
+	file    *os.File
+	buf     *bufio.Writer
+	out     io.Writer
+	quote   string
+	newline string
+	sjis    bool
+}
+
+func newTextWriter(name string, oc OutputConfig) *textWriter {
+	file, err := os.Create(name)
+	failOnError(err)
+
+	w := &textWriter{file: file, buf: bufio.NewWriter(file), quote: oc.Quote, newline: "\r\n"}
+	w.out = w.buf
+	if oc.Newline == "lf" {
+		w.newline = "\n"
+	}
+
+	switch oc.Encoding {
+	case "utf8":
+	default:
+		if oc.Encoding != "" && oc.Encoding != "sjis" {
+			logWarn("文字コードが不明なため Shift_JIS で出力します", "value", oc.Encoding)
+		}
+		// Shift_JIS に無い文字は ? にする
+		w.sjis = true
+		w.out = encoding.ReplaceUnsupported(japanese.ShiftJIS.NewEncoder()).Writer(w.buf)
+	}
+	return w
+}
+
+func (w *textWriter) Header(cols []Column) {
+	w.fields(columnNames(cols))
+}
+
+func (w *textWriter) Row(cols []Column, cRec []string) {
+	w.fields(formatRow(cols, cRec))
+}
+
+// fields は項目をカンマで区切った１行を書く
+func (w *textWriter) fields(cRec []string) {
+	fields := make([]string, len(cRec))
+	for I, s := range cRec {
+		fields[I] = w.quoteField(s)
+	}
+	w.line(strings.Join(fields, ","))
+}
+
+// line は１行を書く
+func (w *textWriter) line(s string) {
+	_, err := io.WriteString(w.out, s+w.newline)
+	failOnError(err)
+}
+
+// quoteField は囲み方の設定に従って項目を " で囲む
+// all:すべて囲む none:囲まない 空欄:カンマ・改行・" を含む項目だけ囲む
+func (w *textWriter) quoteField(s string) string {
+	switch w.quote {
+	case "none":
+		return s
+	case "all":
+	default:
+		if !strings.ContainsAny(s, ",\"\r\n") {
+			return s
+		}
+	}
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+func (w *textWriter) Close() {
+	err := w.buf.Flush()
+	failOnError(err)
+	err = w.file.Close()
+	failOnError(err)
+}
+
+// fixedWriter は固定長のテキストファイルの書き出し
+// 健保の固定長はデータ行だけのため、タイトル行は設定の header が true の場合だけ出す
+// 幅は文字コードでのバイト数。幅を設定しない場合は列ごとの最大の長さにする
+type fixedWriter struct {
+	text   *textWriter
+	layout string // チェック結果の検診名
+	widths []int
+	header bool
+	cols   []Column
+	rows   [][]string
+}
+
+func (w *fixedWriter) Header(cols []Column) {
+	w.cols = cols
+	// 幅の数が違うとレイアウトがずれたファイルになるため出力しない
+	if len(w.widths) > 0 && len(w.widths) != len(cols) {
+		failOnError(fmt.Errorf("%sの固定長の幅の数（%d）が列の数（%d）と違います", w.layout, len(w.widths), len(cols)))
+	}
+}
+
+func (w *fixedWriter) Row(cols []Column, cRec []string) {
+	w.rows = append(w.rows, formatRow(cols, cRec))
+}
+
+func (w *fixedWriter) Close() {
+	// タイトル行も幅に合わせる（幅を設定した場合は長い項目名を切る）
+	if w.header {
+		w.rows = append([][]string{columnNames(w.cols)}, w.rows...)
+	}
+
+	widths := w.widths
+	if len(widths) == 0 {
+		widths = make([]int, len(w.cols))
+		for _, cRec := range w.rows {
+			for I, s := range cRec {
+				if n := w.text.width(s); n > widths[I] {
+					widths[I] = n
+				}
+			}
+		}
+	}
+
+	for J, cRec := range w.rows {
+		var b strings.Builder
+		for I, s := range cRec {
+			padded, cut := w.text.pad(s, widths[I], w.cols[I].Type == colNumber)
+			b.WriteString(padded)
+
+			// 幅に入らず切った値はチェック結果に出す（タイトル行は除く）
+			if cut && !(w.header && J == 0) {
+				key, name := rowKey(w.cols, cRec)
+				addCheckKey(w.layout, key, name, w.cols[I].Name, s, fmt.Sprintf("固定長の幅（%dバイト）を超えるため切りました", widths[I]))
+			}
+		}
+		w.text.line(b.String())
+	}
+	w.text.Close()
+}
+
+// rowKey は出力行の記号-番号とカナ氏名を返す
+func rowKey(cols []Column, cRec []string) (string, string) {
+	find := func(names ...string) int {
+		for _, name := range names {
+			for I, c := range cols {
+				if c.Name == name {
+					return I
+				}
+			}
+		}
+		return -1
+	}
+	return cellAt(cRec, find("事業所記号", "記号")) + "-" + cellAt(cRec, find("証番号", "番号")), cellAt(cRec, find("カナ氏名"))
+}
+
+// width は文字の幅（Shift_JIS なら全角を2バイト、UTF-8 ならバイト数）を返す
+func (w *textWriter) width(s string) int {
+	if !w.sjis {
+		return len(s)
+	}
+	n := 0
+	for _, r := range s {
+		n += sjisWidth(r)
+	}
+	return n
+}
+
+// pad は文字を幅に合わせて空白で埋める（数値は右寄せ）。長すぎる場合は文字の区切りで切り、cut を true にする
+func (w *textWriter) pad(s string, width int, right bool) (padded string, cut bool) {
+	n := 0
+	for I, r := range s {
+		rw := utf8.RuneLen(r)
+		if w.sjis {
+			rw = sjisWidth(r)
+		}
+		if n+rw > width {
+			s = s[:I]
+			cut = true
+			break
+		}
+		n += rw
+	}
+
+	sp := strings.Repeat(" ", width-n)
+	if right {
+		return sp + s, cut
+	}
+	return s + sp, cut
+}
+
+// sjisWidth は Shift_JIS での文字のバイト数を返す（半角カナは1バイト）
+func sjisWidth(r rune) int {
+	if r < 0x80 || (r >= 0xFF61 && r <= 0xFF9F) {
+		return 1
+	}
+	return 2
+}
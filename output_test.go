@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyOutputFlag(t *testing.T) {
+	out := applyOutputFlag(nil, "csv,kenshin=fixed", func(oc *OutputConfig, v string) { oc.Format = v })
+	out = applyOutputFlag(out, "kenshin=utf8", func(oc *OutputConfig, v string) { oc.Encoding = v })
+
+	resetState(t)
+	conf.Output = out
+
+	if oc := outputConfig("kenshin"); oc.Format != "fixed" || oc.Encoding != "utf8" {
+		t.Errorf("outputConfig(kenshin) = %+v", oc)
+	}
+	if oc := outputConfig("gastric"); oc.Format != "csv" || oc.Encoding != "" {
+		t.Errorf("outputConfig(gastric) = %+v", oc)
+	}
+}
+
+func TestFixedWriterHeader(t *testing.T) {
+	resetState(t)
+	cols := []Column{textCol("記号"), numberCol("身長", 1)}
+
+	for _, header := range []bool{false, true} {
+		dir := t.TempDir()
+		w := newTableWriter(dir, "テスト", OutputConfig{Format: "fixed", Encoding: "utf8", Newline: "lf", Header: header})
+		w.Header(cols)
+		w.Row(cols, []string{"3025", "170.5"})
+		w.Close()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+		if len(files) != 1 {
+			t.Fatalf("files = %v", files)
+		}
+		b, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+		// 既定はデータ行だけ、header なら項目名の行が先頭
+		want := []string{"3025170.5"}
+		if header {
+			want = []string{"記号身長", "3025   170.5"}
+		}
+		if strings.Join(lines, "|") != strings.Join(want, "|") {
+			t.Errorf("header %v: lines = %q, want %q", header, lines, want)
+		}
+	}
+}
+
+func TestConfigProblemsOutputKey(t *testing.T) {
+	c := defaultConfig()
+	c.Output = map[string]OutputConfig{"*": {}, "stats": {}, "gastric": {}, "胃がん検診": {}}
+
+	var found []string
+	for _, p := range configProblems(c) {
+		if strings.HasPrefix(p, "output の検診の名前") {
+			found = append(found, p)
+		}
+	}
+	if len(found) != 1 || !strings.Contains(found[0], "胃がん検診") {
+		t.Errorf("configProblems = %v, want 胃がん検診 only", found)
+	}
+}
+
+func TestFixedWriterCut(t *testing.T) {
+	resetState(t)
+	cols := []Column{textCol("事業所記号"), textCol("証番号"), textCol("カナ氏名"), textCol("所見")}
+
+	w := newTableWriter(t.TempDir(), "胃がん検診データ", OutputConfig{Format: "fixed", Widths: []int{4, 3, 10, 6}})
+	w.Header(cols)
+	w.Row(cols, []string{"3025", "101", "ｹﾝﾎﾟ ﾀﾛｳ", "胃炎"})
+	w.Row(cols, []string{"3025", "102", "ｹﾝﾎﾟ ﾊﾅｺ", "胃ポリープ"})
+	w.Close()
+
+	// Shift_JIS で６バイトを超えた所見だけをチェック結果に出す
+	if len(checks) != 1 {
+		t.Fatalf("checks = %v", checks)
+	}
+	c := checks[0]
+	if c.Layout != "胃がん検診" || c.Key != "3025-102" || c.Name != "ｹﾝﾎﾟ ﾊﾅｺ" || c.Field != "所見" || c.Value != "胃ポリープ" {
+		t.Errorf("check = %+v", c)
+	}
+}
+
+func TestConfigProblemsWidths(t *testing.T) {
+	c := defaultConfig()
+	c.Output = map[string]OutputConfig{
+		"*":       {Widths: []int{1, 2}},
+		"gastric": {Widths: []int{6, 10, 8, 8, 1, 30, 1, 10, 1, 128, 20}},
+		"dexa":    {Widths: []int{10, 6}},
+	}
+
+	var found []string
+	for _, p := range configProblems(c) {
+		if strings.Contains(p, "widths") {
+			found = append(found, p)
+		}
+	}
+	// 列の数に合う gastric は問題なし、* と数の違う dexa は問題
+	if len(found) != 2 {
+		t.Errorf("configProblems = %v, want * and dexa", found)
+	}
+}
+
+func TestTextWriterNumbers(t *testing.T) {
+	resetState(t)
+	cols := []Column{textCol("証番号"), numberCol("身長", 1), numberCol("血圧（収縮期）", 0)}
+
+	// csv・固定長でも数値はレイアウトの小数点以下の桁数（xlsx の表示形式と同じ）
+	for format, want := range map[string]string{"csv": "0012,170.0,120", "fixed": "0012170.0120"} {
+		dir := t.TempDir()
+		w := newTableWriter(dir, "テスト", OutputConfig{Format: format, Encoding: "utf8", Newline: "lf"})
+		w.Header(cols)
+		w.Row(cols, []string{"0012", "170", "120.0"})
+		w.Close()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		if len(files) != 1 {
+			t.Fatalf("%s: files = %v", format, files)
+		}
+		b, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if got := lines[len(lines)-1]; got != want {
+			t.Errorf("%s: row = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range は検査値の入力範囲
+type Range struct {
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Decimals int     `json:"decimals"` // 小数点以下の桁数
+	Unit     string  `json:"unit"`
+}
+
+// valueRange は項目の入力範囲を返す
+// 設定ファイルの plausibility.ranges を優先し、無ければ検査項目マスタの測定法での範囲を使う
+func valueRange(name string) (Range, bool) {
+	if r, ok := conf.Plausibility.Ranges[name]; ok {
+		return r, true
+	}
+
+	l, ok := labItem(name)
+	if !ok || l.Range.Max == 0 {
+		return Range{}, false
+	}
+	r := l.Range
+	if r.Unit == "" {
+		r.Unit = l.Unit
+	}
+	return r, true
+}
+
+// checkRanges は出力行の検査値が数値で、入力範囲・桁数に収まっているか調べる
+func checkRanges(layout string, rec []string, header []string, cRec []string) {
+	for I, name := range header {
+		r, ok := valueRange(name)
+		if !ok || cRec[I] == "" {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(cRec[I], 64)
+		if err != nil {
+			addCheck(layout, rec, name, cRec[I], "数値ではありません")
+			continue
+		}
+
+		if v < r.Min || v > r.Max {
+			addCheck(layout, rec, name, cRec[I], fmt.Sprintf("範囲外（%g～%g%s）", r.Min, r.Max, r.Unit))
+		}
+
+		if decimals(cRec[I]) > r.Decimals {
+			addCheck(layout, rec, name, cRec[I], fmt.Sprintf("小数点以下の桁数が多い（%d桁）", r.Decimals))
+		}
+	}
+}
+
+// decimals は数値の文字の小数点以下の桁数を返す
+func decimals(s string) int {
+	pos := strings.Index(s, ".")
+	if pos == -1 {
+		return 0
+	}
+	return len(s) - pos - 1
+}
+
+// checkBMI は身長・体重から計算したBMIと入力のBMIが合っているか調べる
+func checkBMI(layout string, rec []string, cRec []string) {
+	if cRec[28] == "" || cRec[29] == "" || cRec[30] == "" {
+		return
+	}
+
+	h, err1 := strconv.ParseFloat(cRec[28], 64)
+	w, err2 := strconv.ParseFloat(cRec[29], 64)
+	bmi, err3 := strconv.ParseFloat(cRec[30], 64)
+	if err1 != nil || err2 != nil || err3 != nil || h <= 0 {
+		return
+	}
+
+	calc := w / ((h / 100) * (h / 100))
+	if math.Abs(calc-bmi) > conf.Plausibility.BMITolerance {
+		addCheck(layout, rec, "BMI", cRec[30], fmt.Sprintf("身長・体重からの計算値%.1fと合いません", calc))
+	}
+}
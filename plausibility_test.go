@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestCheckRanges(t *testing.T) {
+	resetState(t)
+	conf.Plausibility.Ranges = map[string]Range{"腹囲": {Min: 50, Max: 150, Decimals: 0, Unit: "cm"}}
+
+	rec := testRec("101", nil)
+	header := []string{"カナ氏名", "身長", "体重", "腹囲", "HｂA1ｃ", "血清クレアチニン"}
+	cRec := []string{"ｹﾝﾎﾟ ﾀﾛｳ", "1700", "abc", "80.5", "5.4", ""}
+
+	checkRanges("健診", rec, header, cRec)
+
+	// 身長は範囲外、体重は数値でない、腹囲は設定の範囲で桁数が多い。HｂA1ｃは正常、空欄は調べない
+	want := []struct{ field, message string }{
+		{"身長", "範囲外（100～250cm）"},
+		{"体重", "数値ではありません"},
+		{"腹囲", "小数点以下の桁数が多い（0桁）"},
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("checks = %v", checks)
+	}
+	for I, w := range want {
+		if checks[I].Field != w.field || checks[I].Message != w.message {
+			t.Errorf("checks[%d] = %s %s, want %s %s", I, checks[I].Field, checks[I].Message, w.field, w.message)
+		}
+	}
+}
+
+func TestCheckBMI(t *testing.T) {
+	resetState(t)
+
+	rec := testRec("101", nil)
+	cRec := make([]string, 31)
+	cRec[28], cRec[29] = "170.0", "65.0"
+
+	cRec[30] = "22.5" // 65/1.7^2 = 22.49
+	checkBMI("健診", rec, cRec)
+	if len(checks) != 0 {
+		t.Errorf("BMI 22.5: checks = %v", checks)
+	}
+
+	cRec[30] = "25.0"
+	checkBMI("健診", rec, cRec)
+	if len(checks) != 1 || checks[0].Field != "BMI" {
+		t.Errorf("BMI 25.0: checks = %v", checks)
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	for s, want := range map[string]int{"170": 0, "170.5": 1, "0.90": 2, "": 0} {
+		if got := decimals(s); got != want {
+			t.Errorf("decimals(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
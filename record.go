@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// examRecord は受診者１人分の健診結果
+// 健保に送る健診データ・がん検診データと同じ内容を、項目名と型をつけて持つ
+type examRecord struct {
+	Jushinbi string            `json:"jushinbi"` // 受診日（yyyy-mm-dd）
+	Kigo     string            `json:"kigo"`     // 事業所記号
+	Bango    string            `json:"bango"`    // 証番号
+	Honnin   bool              `json:"honnin"`   // 本人なら true、家族なら false
+	Kana     string            `json:"kana"`     // カナ氏名
+	Sei      string            `json:"sei"`      // 性別 1:男 2:女
+	Birth    string            `json:"birth"`    // 生年月日（yyyy-mm-dd）
+	Results  []examResult      `json:"results"`  // 身体計測・血圧・検査の結果
+	Sogo     string            `json:"sogo"`     // 総合判定
+	Shindan  string            `json:"shindan"`  // 医師の診断
+	Ishi     string            `json:"ishi"`     // 医師名
+	Kiou     []kiouEntry       `json:"kiou"`     // 既往歴
+	Jikaku   string            `json:"jikaku"`   // 自覚症状所見
+	Takaku   string            `json:"takaku"`   // 他覚症状所見
+	Monshin  map[string]string `json:"monshin"`  // 質問票（項目名 → 健診データのコード）
+	Cancers  []examCancer      `json:"cancers"`  // がん検診の結果
+}
+
+// examResult は検査１項目の結果
+// 数値の項目は Value、尿定性・所見の有無などは Text に入る
+type examResult struct {
+	Name   string   `json:"name"`
+	JLAC10 string   `json:"jlac10,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+	Method string   `json:"method,omitempty"`
+	Value  *float64 `json:"value,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// examCancer はがん検診１件の結果
+type examCancer struct {
+	Name     string `json:"name"`     // 検診名
+	Jushinbi string `json:"jushinbi"` // 受診日（yyyy-mm-dd）
+	Result   string `json:"result"`   // 結果 1所見なし～6治療中
+	Findings string `json:"findings"` // 所見
+	Kensa    string `json:"kensa"`    // 検査区分
+}
+
+// 健診データの結果の列（身長～便潜血）と質問票の列（服薬・血圧～指導受診歴）
+const (
+	resultFrom  = 28
+	resultTo    = 64
+	monshinFrom = 76
+	monshinTo   = 97
+)
+
+// buildExamRecords は健診データの出力行ごとに健診結果を作り、がん検診の結果をつける
+func buildExamRecords(outRecs map[string][]outRow) []examRecord {
+	cols := kenshinLayout{}.Columns()
+
+	var recs []examRecord
+	index := map[string]int{}
+	for _, row := range outRecs["健診"] {
+		index[row.CRec[3]+"-"+row.CRec[4]] = len(recs)
+		recs = append(recs, newExamRecord(row, cols))
+	}
+
+	// がん検診は記号・番号で健診結果につける（受診日が違っても同じ人）
+	for _, l := range layouts() {
+		if _, ok := l.(cancerLayout); !ok {
+			continue
+		}
+		for _, row := range outRecs[l.Name()] {
+			I, ok := index[row.CRec[2]+"-"+row.CRec[3]]
+			if !ok {
+				continue
+			}
+			recs[I].Cancers = append(recs[I].Cancers, examCancer{
+				Name:     l.Name(),
+				Jushinbi: isoDate(row.CRec[1]),
+				Result:   row.CRec[8],
+				Findings: row.CRec[9],
+				Kensa:    row.CRec[10],
+			})
+		}
+	}
+
+	return recs
+}
+
+// newExamRecord は健診データの出力行１件を健診結果にする
+func newExamRecord(row outRow, cols []Column) examRecord {
+	cRec := row.CRec
+	r := examRecord{
+		Jushinbi: isoDate(cRec[2]),
+		Kigo:     cRec[3],
+		Bango:    cRec[4],
+		Honnin:   cRec[5] == "0",
+		Kana:     cRec[9],
+		Sei:      cRec[10],
+		Birth:    isoDate(cRec[11]),
+		Sogo:     cRec[65],
+		Shindan:  cRec[67],
+		Ishi:     cRec[68],
+		Kiou:     parseKiou(row.Rec),
+		Jikaku:   cRec[72],
+		Takaku:   cRec[74],
+		Monshin:  map[string]string{},
+	}
+
+	for I := resultFrom; I <= resultTo; I++ {
+		if cRec[I] == "" || cols[I].Name == "身体検査判定" || cols[I].Name == "未実施の場合その理由" {
+			continue
+		}
+
+		res := examResult{Name: cols[I].Name}
+		if l, ok := labItem(cols[I].Name); ok {
+			res.JLAC10 = l.JLAC10
+			res.Unit = l.Unit
+			res.Method = l.Method
+		}
+		if v, err := strconv.ParseFloat(cRec[I], 64); err == nil && cols[I].Type == colNumber {
+			res.Value = &v
+		} else {
+			res.Text = cRec[I]
+		}
+		r.Results = append(r.Results, res)
+	}
+
+	for I := monshinFrom; I <= monshinTo; I++ {
+		if cRec[I] != "" {
+			r.Monshin[cols[I].Name] = cRec[I]
+		}
+	}
+
+	return r
+}
+
+// departments は入力ファイルの受診者（記号-番号）ごとの所属を返す
+func departments(records [][]string) map[string]string {
+	shozoku := map[string]string{}
+	for J := 1; J < len(records); J++ {
+		if bango := colValue(records[J], 6); bango != "" {
+			shozoku[colValue(records[J], 5)+"-"+bango] = department(records[J])
+		}
+	}
+	return shozoku
+}
+
+// department は受診者の所属を返す
+// 設定で入力ファイルの列を指定していればその列、無ければ事業所記号の所属の一覧から
+func department(rec []string) string {
+	if s := colValue(rec, conf.Department.Column); s != "" {
+		return s
+	}
+	return conf.Department.Names[colValue(rec, 5)]
+}
+
+// isoDate は yyyy/mm/dd の日付を yyyy-mm-dd にする
+func isoDate(s string) string {
+	return strings.Replace(s, "/", "-", -1)
+}
+
+// writeExamRecords は健診結果を JSON（配列）または NDJSON（１人１行）で出力する
+func writeExamRecords(filename string, recs []examRecord, format string) {
+	jsonName, _ := filepath.Split(filename)
+	jsonName = outputFile(jsonName, "健診結果", "."+format)
+
+	file, err := os.Create(jsonName)
+	failOnError(err)
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	if format == "ndjson" {
+		enc := json.NewEncoder(w)
+		for _, r := range recs {
+			err = enc.Encode(r)
+			failOnError(err)
+		}
+	} else {
+		b, err := json.MarshalIndent(recs, "", "  ")
+		failOnError(err)
+		_, err = w.Write(append(b, '\n'))
+		failOnError(err)
+	}
+
+	err = w.Flush()
+	failOnError(err)
+	logInfo("健診結果", "format", format, "count", len(recs))
+}
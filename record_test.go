@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestDepartments(t *testing.T) {
+	resetState(t)
+	conf.Department.Names = map[string]string{"3025": "本部"}
+
+	records := [][]string{
+		{"header"},
+		{"", "", "", "", "2024-05-10", "3025", "101"},
+		{"", "", "", "", "2024-05-10", "3025", ""},
+		{"", "", "", "", "2024-05-10"}, // 列の足りない行
+	}
+	got := departments(records)
+	if len(got) != 1 || got["3025-101"] != "本部" {
+		t.Errorf("departments = %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/tealeg/xlsx"
+)
+
+// requiredItem は特定健診の必須項目
+// Cols は健診データの列で、どれか１つに値があれば実施済みとする
+type requiredItem struct {
+	Name string
+	Cols []int
+}
+
+// requiredItems は特定健診の必須項目（③必須項目・未受診理由入力シートの項目）
+var requiredItems = []requiredItem{
+	{"身長", []int{28}},
+	{"体重", []int{29}},
+	{"BMI", []int{30}},
+	{"腹囲", []int{31}},
+	{"血圧（収縮期）", []int{33}},
+	{"血圧（拡張期）", []int{34}},
+	{"中性脂肪", []int{35, 36}},
+	{"HDL・CO", []int{37}},
+	{"LDL・CO", []int{38, 39}},
+	{"AST(GOT)", []int{40}},
+	{"ALT(GPT)", []int{41}},
+	{"γ・GTP", []int{42}},
+	{"血糖", []int{43, 44, 45}},
+	{"尿糖", []int{47}},
+	{"尿蛋白", []int{48}},
+	{"服薬・血圧", []int{76}},
+	{"服薬・血糖", []int{77}},
+	{"服薬・コレステロール", []int{78}},
+	{"たばこ", []int{83}},
+}
+
+// requiredMissing は必須項目の未実施１件（③シートの１行）
+type requiredMissing struct {
+	Kigo   string
+	Bango  string
+	Name   string
+	Item   string
+	Reason string
+}
+
+// 必須項目の未実施と、採血時間3.5時間未満の受診者
+var (
+	missings   []requiredMissing
+	shortMeals []requiredMissing
+)
+
+// checkRequired は健診データの必須項目が揃っているか調べる
+func checkRequired(rec []string, cRec []string) {
+	for _, item := range requiredItems {
+		done := false
+		for _, c := range item.Cols {
+			if cRec[c] != "" {
+				done = true
+			}
+		}
+		if done {
+			continue
+		}
+
+		// 尿検査は未実施の理由があれば健診データ（未実施の場合その理由）に入るので③には書かない
+		if (item.Name == "尿糖" || item.Name == "尿蛋白") && cRec[49] != "" {
+			continue
+		}
+
+		m := requiredMissing{
+			Kigo:   rec[5],
+			Bango:  rec[6],
+			Name:   cRec[9],
+			Item:   item.Name,
+			Reason: colValue(rec, conf.Required.Reasons[item.Name]),
+		}
+		missings = append(missings, m)
+		addCheck("健診", rec, item.Name, m.Reason, "必須項目の未実施")
+	}
+
+	// 食後3.5時間未満の採血は随時中性脂肪で評価するため③に名簿を書く
+	eattime, err := strconv.ParseFloat(rec[28], 64)
+	if rec[27] == "とった" && err == nil && eattime < 3.5 {
+		shortMeals = append(shortMeals, requiredMissing{Kigo: rec[5], Bango: rec[6], Name: cRec[9]})
+	}
+}
+
+// writeRequired は③必須項目・未受診理由入力シートの内容を出力する（該当者が無ければ作らない）
+func writeRequired(filename string) {
+	if len(missings) == 0 && len(shortMeals) == 0 {
+		return
+	}
+
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "必須項目未受診データ", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+
+	sheet, err := excelFile.AddSheet("必須項目・未受診理由")
+	failOnError(err)
+	addRow(sheet, []string{"記号", "番号", "名前", "項目", "理由", "左記でその他を選択した場合は詳細入力"})
+	for _, m := range missings {
+		addRow(sheet, []string{m.Kigo, m.Bango, m.Name, m.Item, m.Reason, ""})
+	}
+
+	sheet, err = excelFile.AddSheet("採血時間3.5時間未満")
+	failOnError(err)
+	addRow(sheet, []string{"記号", "番号", "名前"})
+	for _, m := range shortMeals {
+		addRow(sheet, []string{m.Kigo, m.Bango, m.Name})
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+// requiredRow は必須項目をすべて入れた健診データの１行
+func requiredRow() []string {
+	cRec := make([]string, 100)
+	cRec[9] = "ｹﾝﾎﾟ ﾀﾛｳ"
+	for _, item := range requiredItems {
+		cRec[item.Cols[0]] = "1"
+	}
+	return cRec
+}
+
+func TestCheckRequired(t *testing.T) {
+	tests := []struct {
+		name    string
+		reasons map[string]int
+		blank   []int // 空欄にする健診データの列
+		items   string
+		reason  string
+	}{
+		{"揃っている", nil, nil, "", ""},
+		{"理由の列なし", nil, []int{31}, "腹囲", ""},
+		{"理由の列あり", map[string]int{"腹囲": 150}, []int{31}, "腹囲", "妊娠中"},
+		{"どれか１つの列", nil, []int{35}, "", ""},       // 中性脂肪は随時（36列）があれば実施済み
+		{"尿検査の未実施理由", nil, []int{47, 48}, "", ""}, // 49列に理由があれば③には書かない
+		{"血糖なし", nil, []int{43}, "", ""},          // 44・45列があれば実施済み
+		{"尿検査の理由なし", nil, []int{47, 48, 49}, "尿糖,尿蛋白", ""},
+		{"複数", nil, []int{28, 33}, "身長,血圧（収縮期）", ""},
+	}
+	for _, tt := range tests {
+		resetState(t)
+		conf.Required.Reasons = tt.reasons
+
+		rec := testRec("101", map[int]string{150: "妊娠中"})
+		cRec := requiredRow()
+		cRec[36], cRec[44], cRec[49] = "1", "1", "妊娠中のため"
+		for _, c := range tt.blank {
+			cRec[c] = ""
+		}
+		checkRequired(rec, cRec)
+
+		var items []string
+		for _, m := range missings {
+			items = append(items, m.Item)
+			if m.Kigo != "3025" || m.Bango != "101" || m.Name != "ｹﾝﾎﾟ ﾀﾛｳ" || m.Reason != tt.reason {
+				t.Errorf("%s: missing = %+v", tt.name, m)
+			}
+		}
+		if got := strings.Join(items, ","); got != tt.items {
+			t.Errorf("%s: items = %q, want %q", tt.name, got, tt.items)
+		}
+		if len(checks) != len(missings) {
+			t.Errorf("%s: checks = %d, missings = %d", tt.name, len(checks), len(missings))
+		}
+	}
+}
+
+func TestCheckRequiredShortMeal(t *testing.T) {
+	tests := []struct {
+		shokuji, jikan string
+		short          bool
+	}{
+		{"とった", "2.5", true},
+		{"とった", "3.5", false},
+		{"とった", "", false},
+		{"とらない", "2", false},
+	}
+	for _, tt := range tests {
+		resetState(t)
+		checkRequired(testRec("101", map[int]string{27: tt.shokuji, 28: tt.jikan}), requiredRow())
+		if got := len(shortMeals) == 1; got != tt.short {
+			t.Errorf("%s %s: shortMeals = %v", tt.shokuji, tt.jikan, shortMeals)
+		}
+	}
+}
+
+func TestWriteRequired(t *testing.T) {
+	resetState(t)
+	dir := t.TempDir()
+
+	// 該当者が無ければ作らない
+	writeRequired(filepath.Join(dir, "x"))
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.xlsx")); len(files) != 0 {
+		t.Fatalf("files = %v, want none", files)
+	}
+
+	conf.Required.Reasons = map[string]int{"腹囲": 150}
+	cRec := requiredRow()
+	cRec[31] = ""
+	checkRequired(testRec("101", map[int]string{150: "妊娠中", 27: "とった", 28: "2"}), cRec)
+	writeRequired(filepath.Join(dir, "x"))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.xlsx"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v", files)
+	}
+	f, err := xlsx.OpenFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Sheets) != 2 {
+		t.Fatalf("sheets = %d, want 2", len(f.Sheets))
+	}
+
+	rowText := func(r *xlsx.Row) string {
+		var s []string
+		for _, c := range r.Cells {
+			s = append(s, c.String())
+		}
+		return strings.Join(s, ",")
+	}
+	if rows := f.Sheets[0].Rows; len(rows) != 2 || rowText(rows[1]) != "3025,101,ｹﾝﾎﾟ ﾀﾛｳ,腹囲,妊娠中," {
+		t.Errorf("必須項目・未受診理由 = %d rows", len(rows))
+	}
+	if rows := f.Sheets[1].Rows; len(rows) != 2 || rowText(rows[1]) != "3025,101,ｹﾝﾎﾟ ﾀﾛｳ" {
+		t.Errorf("採血時間3.5時間未満 = %d rows", len(rows))
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+)
+
+// statTable は健康統計の表１つ（xlsx ではシート、csv ではファイル）
+type statTable struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+// statGroup は健康統計を数える受診者の集まり（年代・性別・年度など）
+type statGroup struct {
+	total     int
+	saiken    int // 要再検
+	chiryo    int // 要治療
+	himan     int // 肥満（BMI 25以上）
+	ketsuatsu int // 高血圧（140/90 以上または服薬）
+	shishitsu int // 脂質異常（LDL 140以上・HDL 40未満・中性脂肪 150以上または服薬）
+	kitsuen   int // 喫煙（たばこ はい）
+}
+
+// 年代の区切り（上限の年齢。最後は上限なし）
+var ageBands = []struct {
+	name string
+	max  int
+}{
+	{"～29歳", 29},
+	{"30～39歳", 39},
+	{"40～49歳", 49},
+	{"50～59歳", 59},
+	{"60歳～", 999},
+}
+
+// 質問票の回答（健診データのコード → 回答）
+var (
+	tabakoNames  = []string{"", "はい", "以前あり", "いいえ"}
+	sakeNames    = []string{"", "毎日", "週５～６日", "週３～４日", "週１～２日", "月に１～３日", "月に１日未満", "やめた", "飲まない"}
+	sakeryoNames = []string{"", "１合未満", "１～２合未満", "２～３合未満", "３～５合未満", "５合以上"}
+)
+
+// writeStats は健診結果から衛生委員会向けの健康統計を出力する
+// 年代・性別ごとの判定・肥満・高血圧・脂質異常、喫煙・飲酒の回答、年度ごとの変化
+// 年度ごとの変化は履歴を使っている場合は前年度以前も含める。受診者は年度ごとに１人と数える
+func writeStats(filename string, exams []examRecord) {
+	if len(exams) == 0 {
+		return
+	}
+
+	current := latestExams(exams)
+	tables := []statTable{
+		ageSexTable(current),
+		habitTable(current),
+		trendTable(latestExams(statHistory(exams))),
+	}
+
+	dir, _ := filepath.Split(filename)
+	if conf.Stats.Format == "csv" {
+		oc := outputConfig("stats")
+		oc.Format = "csv"
+		for _, t := range tables {
+			var cols []Column
+			for _, h := range t.header {
+				cols = append(cols, textCol(h))
+			}
+			w := newTableWriter(dir, "健康統計_"+t.name, oc)
+			w.Header(cols)
+			for _, row := range t.rows {
+				w.Row(cols, row)
+			}
+			w.Close()
+		}
+	} else {
+		excelName := outputFile(dir, "健康統計", ".xlsx")
+		excelFile := xlsx.NewFile()
+		xlsx.SetDefaultFont(11, "游ゴシック")
+		for _, t := range tables {
+			sheet, err := excelFile.AddSheet(t.name)
+			failOnError(err)
+			addRow(sheet, t.header)
+			for _, row := range t.rows {
+				addStatRow(sheet, row)
+			}
+		}
+		err := excelFile.Save(excelName)
+		failOnError(err)
+	}
+
+	logInfo("健康統計", "count", len(current), "format", conf.Stats.Format)
+}
+
+// addStatRow は数値の項目を数値のセルにして行を追加する（グラフにするため）
+func addStatRow(sheet *xlsx.Sheet, cRec []string) {
+	row := sheet.AddRow()
+	for _, s := range cRec {
+		vcell := row.AddCell()
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			vcell.SetFloat(v)
+		} else {
+			vcell.Value = s
+		}
+	}
+}
+
+// ageSexTable は年代・性別ごとの人数と要再検・要治療・肥満・高血圧・脂質異常の人数と割合
+// 性別が男・女でない人は 不明 の行に数える（計と各行の合計を合わせる）
+func ageSexTable(exams []examRecord) statTable {
+	t := statTable{name: "年代・性別", header: append([]string{"年代", "性別"}, statHeader()...)}
+
+	groups := map[string]*statGroup{}
+	for _, r := range exams {
+		sei := r.Sei
+		if sei != "1" && sei != "2" {
+			sei = "不明"
+		}
+		for _, key := range []string{ageBand(r) + "/" + sei, "計/" + sei, "計/"} {
+			if groups[key] == nil {
+				groups[key] = &statGroup{}
+			}
+			groups[key].add(r)
+		}
+	}
+
+	for _, band := range append(bandNames(), "計") {
+		for _, s := range []string{"1", "2", "不明", ""} {
+			g, ok := groups[band+"/"+s]
+			if !ok {
+				continue
+			}
+			name := seiName(s)
+			if s == "不明" {
+				name = s
+			}
+			t.rows = append(t.rows, append([]string{band, name}, g.cells()...))
+		}
+	}
+	return t
+}
+
+// habitTable は喫煙・飲酒の回答ごとの人数（男女別）と割合
+func habitTable(exams []examRecord) statTable {
+	t := statTable{name: "喫煙・飲酒", header: []string{"質問", "回答", "男", "女", "計", "割合（%）"}}
+
+	questions := []struct {
+		name    string
+		answers []string
+	}{
+		{"たばこ", tabakoNames},
+		{"お酒・頻度", sakeNames},
+		{"お酒・量", sakeryoNames},
+	}
+	for _, q := range questions {
+		counts := map[string][2]int{}
+		total := 0
+		for _, r := range exams {
+			code := r.Monshin[q.name]
+			if code == "" {
+				continue
+			}
+			c := counts[code]
+			if r.Sei == "2" {
+				c[1]++
+			} else {
+				c[0]++
+			}
+			counts[code] = c
+			total++
+		}
+
+		for _, code := range sortedCodes(counts) {
+			answer := code
+			if I, err := strconv.Atoi(code); err == nil && I > 0 && I < len(q.answers) {
+				answer = q.answers[I]
+			}
+			c := counts[code]
+			t.rows = append(t.rows, []string{q.name, answer, strconv.Itoa(c[0]), strconv.Itoa(c[1]), strconv.Itoa(c[0] + c[1]), percent(c[0]+c[1], total)})
+		}
+	}
+	return t
+}
+
+// trendTable は年度ごとの人数と要再検・要治療・肥満・高血圧・脂質異常・喫煙の割合
+func trendTable(exams []examRecord) statTable {
+	t := statTable{name: "経年変化", header: append([]string{"年度"}, statHeader()...)}
+
+	groups := map[int]*statGroup{}
+	var years []int
+	for _, r := range exams {
+		y := nendo(r.Jushinbi)
+		if groups[y] == nil {
+			groups[y] = &statGroup{}
+			years = append(years, y)
+		}
+		groups[y].add(r)
+	}
+	sort.Ints(years)
+
+	for _, y := range years {
+		t.rows = append(t.rows, append([]string{strconv.Itoa(y)}, groups[y].cells()...))
+	}
+	return t
+}
+
+// statHistory は年度ごとの変化に使う健診結果（履歴の前年度以前の受診と今回の健診結果）
+func statHistory(exams []examRecord) []examRecord {
+	if !conf.History.Enabled || !historyExists() {
+		return exams
+	}
+
+	db := openHistory()
+	defer db.Close()
+
+	var recs []examRecord
+	for _, entries := range loadHistory(db, "") {
+		for _, e := range entries {
+			recs = append(recs, e.Record)
+		}
+	}
+	// 今回の健診結果を後に置いて履歴の同じ受診を置き換える
+	return append(recs, exams...)
+}
+
+// latestExams は受診者・年度ごとに最後の健診結果だけを返す（分割受診を１人と数える）
+func latestExams(exams []examRecord) []examRecord {
+	latest := map[string]examRecord{}
+	for _, r := range exams {
+		key := personKey(r) + "/" + strconv.Itoa(nendo(r.Jushinbi))
+		if old, ok := latest[key]; !ok || old.Jushinbi <= r.Jushinbi {
+			latest[key] = r
+		}
+	}
+
+	keys := make([]string, 0, len(latest))
+	for k := range latest {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	recs := make([]examRecord, 0, len(keys))
+	for _, k := range keys {
+		recs = append(recs, latest[k])
+	}
+	return recs
+}
+
+func statHeader() []string {
+	return []string{
+		"人数",
+		"要再検", "要治療", "要再検・要治療（%）",
+		"肥満", "肥満（%）",
+		"高血圧", "高血圧（%）",
+		"脂質異常", "脂質異常（%）",
+		"喫煙", "喫煙（%）",
+	}
+}
+
+// add は受診者１人を数える
+func (g *statGroup) add(r examRecord) {
+	g.total++
+
+	switch r.Sogo {
+	case "要再検":
+		g.saiken++
+	case "要治療":
+		g.chiryo++
+	}
+
+	if v, ok := resultValue(r, "BMI"); ok && v >= 25 {
+		g.himan++
+	}
+
+	sbp, _ := resultValue(r, "血圧（収縮期）")
+	dbp, _ := resultValue(r, "血圧（拡張期）")
+	if sbp >= 140 || dbp >= 90 || r.Monshin["服薬・血圧"] == "1" {
+		g.ketsuatsu++
+	}
+
+	ldl, _ := resultValue(r, "LDL・CO")
+	hdl, okHDL := resultValue(r, "HDL・CO")
+	tg, _ := resultValue(r, "空腹時中性脂肪", "随時中性脂肪")
+	if ldl >= 140 || (okHDL && hdl < 40) || tg >= 150 || r.Monshin["服薬・コレステロール"] == "1" {
+		g.shishitsu++
+	}
+
+	if r.Monshin["たばこ"] == "1" {
+		g.kitsuen++
+	}
+}
+
+// cells は人数・件数・割合を表の項目にする
+func (g *statGroup) cells() []string {
+	return []string{
+		strconv.Itoa(g.total),
+		strconv.Itoa(g.saiken), strconv.Itoa(g.chiryo), percent(g.saiken+g.chiryo, g.total),
+		strconv.Itoa(g.himan), percent(g.himan, g.total),
+		strconv.Itoa(g.ketsuatsu), percent(g.ketsuatsu, g.total),
+		strconv.Itoa(g.shishitsu), percent(g.shishitsu, g.total),
+		strconv.Itoa(g.kitsuen), percent(g.kitsuen, g.total),
+	}
+}
+
+// resultValue は健診結果の検査の値を返す（最初に見つかった項目名）
+func resultValue(r examRecord, names ...string) (float64, bool) {
+	for _, name := range names {
+		for _, res := range r.Results {
+			if res.Name == name && res.Value != nil {
+				return *res.Value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ageBand は受診日時点の年代を返す（生年月日が分からなければ 不明）
+func ageBand(r examRecord) string {
+	a, ok := age(strings.Replace(r.Birth, "-", "/", -1), r.Jushinbi)
+	if !ok {
+		return "不明"
+	}
+	for _, b := range ageBands {
+		if a <= b.max {
+			return b.name
+		}
+	}
+	return "不明"
+}
+
+func bandNames() []string {
+	var names []string
+	for _, b := range ageBands {
+		names = append(names, b.name)
+	}
+	return append(names, "不明")
+}
+
+func seiName(s string) string {
+	switch s {
+	case "1":
+		return "男"
+	case "2":
+		return "女"
+	}
+	return "計"
+}
+
+// percent は割合（%）を小数点以下1桁で返す（分母が0なら空欄）
+func percent(n int, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", float64(n)*100/float64(total))
+}
+
+// sortedCodes は質問票のコードを数値の順に並べて返す
+func sortedCodes(m map[string][2]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		ia, errA := strconv.Atoi(keys[a])
+		ib, errB := strconv.Atoi(keys[b])
+		if errA == nil && errB == nil {
+			return ia < ib
+		}
+		return keys[a] < keys[b]
+	})
+	return keys
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// statExam は健康統計のテスト用の健診結果
+func statExam(bango string, jushinbi string, sei string, birth string, sogo string, bmi float64, tabako string) examRecord {
+	return examRecord{
+		Jushinbi: jushinbi,
+		Kigo:     "3025",
+		Bango:    bango,
+		Sei:      sei,
+		Birth:    birth,
+		Sogo:     sogo,
+		Results:  []examResult{{Name: "BMI", Value: &bmi}},
+		Monshin:  map[string]string{"たばこ": tabako},
+	}
+}
+
+// statRow は表の先頭の項目が keys の行を返す
+func statRow(t *testing.T, table statTable, keys ...string) []string {
+	t.Helper()
+	for _, row := range table.rows {
+		if strings.Join(row[:len(keys)], "/") == strings.Join(keys, "/") {
+			return row
+		}
+	}
+	t.Fatalf("%s: no row %v in %v", table.name, keys, table.rows)
+	return nil
+}
+
+func TestAgeSexTable(t *testing.T) {
+	exams := []examRecord{
+		statExam("101", "2024-05-10", "1", "1990-01-01", "要再検", 26, "1"), // 34歳
+		statExam("102", "2024-05-10", "1", "1985-06-01", "", 22, "3"),    // 38歳
+		statExam("103", "2024-05-10", "2", "1970-01-01", "要治療", 21, ""),  // 54歳
+		statExam("104", "2024-05-10", "err", "1990-01-01", "", 30, ""),   // 性別不明
+		statExam("105", "2024-05-10", "2", "", "", 20, ""),               // 生年月日なし
+	}
+	table := ageSexTable(exams)
+
+	tests := []struct {
+		band, sei string
+		total     string
+		himan     string
+	}{
+		{"30～39歳", "男", "2", "1"},
+		{"30～39歳", "不明", "1", "1"},
+		{"50～59歳", "女", "1", "0"},
+		{"不明", "女", "1", "0"},
+		{"計", "男", "2", "1"},
+		{"計", "女", "2", "0"},
+		{"計", "不明", "1", "1"},
+		{"計", "計", "5", "2"},
+	}
+	for _, tt := range tests {
+		row := statRow(t, table, tt.band, tt.sei)
+		// 人数は３列目、肥満は７列目
+		if row[2] != tt.total || row[6] != tt.himan {
+			t.Errorf("%s/%s: 人数 %s 肥満 %s, want %s %s", tt.band, tt.sei, row[2], row[6], tt.total, tt.himan)
+		}
+	}
+
+	// 年代の行の人数の合計は計と同じ
+	sum := 0
+	for _, row := range table.rows {
+		if row[0] != "計" && row[1] != "計" {
+			n, _ := strconv.Atoi(row[2])
+			sum += n
+		}
+	}
+	if sum != 5 {
+		t.Errorf("band rows total %d, want 5", sum)
+	}
+}
+
+func TestHabitTable(t *testing.T) {
+	exams := []examRecord{
+		statExam("101", "2024-05-10", "1", "1990-01-01", "", 22, "1"),
+		statExam("102", "2024-05-10", "1", "1990-01-01", "", 22, "3"),
+		statExam("103", "2024-05-10", "2", "1990-01-01", "", 22, "1"),
+		statExam("104", "2024-05-10", "2", "1990-01-01", "", 22, ""), // 無回答は数えない
+	}
+	table := habitTable(exams)
+
+	if row := statRow(t, table, "たばこ", "はい"); strings.Join(row[2:], ",") != "1,1,2,66.7" {
+		t.Errorf("たばこ はい = %v", row)
+	}
+	if row := statRow(t, table, "たばこ", "いいえ"); strings.Join(row[2:], ",") != "1,0,1,33.3" {
+		t.Errorf("たばこ いいえ = %v", row)
+	}
+	if len(table.rows) != 2 {
+		t.Errorf("rows = %v, want たばこ only", table.rows)
+	}
+}
+
+func TestTrendTable(t *testing.T) {
+	exams := []examRecord{
+		statExam("101", "2023-05-10", "1", "1990-01-01", "要再検", 26, "1"),
+		statExam("101", "2024-05-10", "1", "1990-01-01", "", 22, "3"),
+		statExam("102", "2025-03-01", "2", "1990-01-01", "", 22, ""), // 2024年度
+	}
+	table := trendTable(exams)
+
+	if len(table.rows) != 2 {
+		t.Fatalf("rows = %v, want 2023 and 2024", table.rows)
+	}
+	if row := table.rows[0]; row[0] != "2023" || row[1] != "1" || row[4] != "100.0" {
+		t.Errorf("2023 = %v", row)
+	}
+	if row := table.rows[1]; row[0] != "2024" || row[1] != "2" || row[4] != "0.0" {
+		t.Errorf("2024 = %v", row)
+	}
+}
+
+func TestLatestExams(t *testing.T) {
+	exams := []examRecord{
+		statExam("101", "2024-06-01", "1", "1990-01-01", "要再検", 26, ""),
+		statExam("101", "2024-05-10", "1", "1990-01-01", "", 22, ""),
+		statExam("101", "2025-05-10", "1", "1990-01-01", "", 22, ""), // 次の年度
+		statExam("102", "2024-05-10", "2", "1990-01-01", "", 22, ""),
+	}
+	got := latestExams(exams)
+
+	// 受診者・年度ごとに最後の受診の１件
+	if len(got) != 3 {
+		t.Fatalf("latestExams = %d, want 3", len(got))
+	}
+	want := []string{"3025-101/2024-06-01", "3025-101/2025-05-10", "3025-102/2024-05-10"}
+	for I, r := range got {
+		if s := personKey(r) + "/" + r.Jushinbi; s != want[I] {
+			t.Errorf("latestExams[%d] = %s, want %s", I, s, want[I])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tealeg/xlsx"
+)
+
+// 総合判定の並び（判定ランクの順）
+var sogoOrder = []string{"所見なし", "略正常", "要観察", "治療中", "要再検", "要治療"}
+
+// writeSummary は変換結果の集計（提出書類の表紙）をエクセルファイルに出力する
+// 入力行数・検診ごとの件数（本人・家族）・除外の理由・チェック結果の項目・総合判定・金額の合計
+// 除外は出力しなかった行（対象外の条件・証番号が空欄・統合・重複）で、検診を受診していない人は数えない
+func writeSummary(filename string, r runResult, selected []Layout, outRecs map[string][]outRow, records [][]string) {
+	excelName, _ := filepath.Split(filename)
+	excelName = outputFile(excelName, "集計", ".xlsx")
+	excelFile := xlsx.NewFile()
+	xlsx.SetDefaultFont(11, "游ゴシック")
+	sheet, err := excelFile.AddSheet("集計")
+	failOnError(err)
+
+	// 実行
+	blank := 0
+	for J := 1; J < len(records); J++ {
+		if records[J][6] == "" {
+			blank++
+		}
+	}
+	addRow(sheet, []string{"実行日時", runTime.Format("2006/01/02 15:04:05")})
+	addRow(sheet, []string{"実行ID", runID})
+	for _, path := range r.Inputs {
+		in := findInput(path)
+		addRow(sheet, []string{"抽出データ", filepath.Base(path), strconv.Itoa(in.Rows) + "行"})
+	}
+	addRow(sheet, []string{"入力行数", strconv.Itoa(len(records) - 1)})
+	addRow(sheet, []string{"証番号が空欄（出力しない）", strconv.Itoa(blank)})
+
+	// 検診ごとの件数
+	addRow(sheet, nil)
+	addRow(sheet, []string{"出力", "件数", "本人", "家族"})
+	for _, l := range selected {
+		rows := outRecs[l.Name()]
+		honnin, kazoku := 0, 0
+		if I := columnIndex(l.Columns(), "資格区分", "本人家族"); I >= 0 {
+			for _, row := range rows {
+				if row.CRec[I] == "1" {
+					kazoku++
+				} else {
+					honnin++
+				}
+			}
+		}
+		addRow(sheet, []string{l.Name() + "データ", strconv.Itoa(len(rows)), strconv.Itoa(honnin), strconv.Itoa(kazoku)})
+	}
+
+	// 除外の理由
+	addRow(sheet, nil)
+	addRow(sheet, []string{"除外", "理由", "件数"})
+	excluded := map[[2]string]int{}
+	for _, e := range exclusions {
+		excluded[[2]string{e.Layout, e.Reason}]++
+	}
+	for _, k := range sortedPairs(excluded, selected) {
+		addRow(sheet, []string{k[0], k[1], strconv.Itoa(excluded[k])})
+	}
+
+	// チェック結果（項目ごと）
+	addRow(sheet, nil)
+	addRow(sheet, []string{"チェック結果", "項目", "件数"})
+	fields := map[[2]string]int{}
+	for _, c := range checks {
+		fields[[2]string{c.Layout, c.Field}]++
+	}
+	for _, k := range sortedPairs(fields, selected) {
+		addRow(sheet, []string{k[0], k[1], strconv.Itoa(fields[k])})
+	}
+	addRow(sheet, []string{"必須項目の未実施", "", strconv.Itoa(len(missings))})
+
+	// 総合判定
+	if rows, ok := outRecs["健診"]; ok {
+		addRow(sheet, nil)
+		addRow(sheet, []string{"総合判定", "件数"})
+		I := columnIndex((kenshinLayout{}).Columns(), "総合判定")
+		sogo := map[string]int{}
+		for _, row := range rows {
+			sogo[row.CRec[I]]++
+		}
+		for _, s := range sogoKeys(sogo) {
+			addRow(sheet, []string{s, strconv.Itoa(sogo[s])})
+		}
+	}
+
+	// 金額の合計（金額の列ごと）
+	addRow(sheet, nil)
+	addRow(sheet, []string{"金額", "項目", "合計"})
+	for _, l := range selected {
+		for I, col := range l.Columns() {
+			if col.Type != colNumber || !strings.HasSuffix(col.Name, "金額") {
+				continue
+			}
+			sum := 0
+			for _, row := range outRecs[l.Name()] {
+				v, _ := strconv.Atoi(row.CRec[I])
+				sum += v
+			}
+			addRow(sheet, []string{l.Name() + "データ", col.Name, strconv.Itoa(sum)})
+		}
+	}
+
+	err = excelFile.Save(excelName)
+	failOnError(err)
+	logInfo("集計", "path", excelName)
+}
+
+// columnIndex はレイアウトの列で最初に見つかった項目名の位置を返す（無ければ -1）
+func columnIndex(cols []Column, names ...string) int {
+	for _, name := range names {
+		for I, col := range cols {
+			if col.Name == name {
+				return I
+			}
+		}
+	}
+	return -1
+}
+
+// sortedPairs は 検診名・項目 のキーを検診の順、項目の名前の順に並べて返す
+func sortedPairs(m map[[2]string]int, selected []Layout) [][2]string {
+	order := map[string]int{}
+	for I, l := range selected {
+		order[l.Name()] = I + 1
+	}
+
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		oa, ob := order[keys[a][0]], order[keys[b][0]]
+		if oa != ob {
+			// 検診以外（入力・履歴など）は後ろ
+			if oa == 0 || ob == 0 {
+				return ob == 0
+			}
+			return oa < ob
+		}
+		if keys[a][0] != keys[b][0] {
+			return keys[a][0] < keys[b][0]
+		}
+		return keys[a][1] < keys[b][1]
+	})
+	return keys
+}
+
+// sogoKeys は総合判定を判定ランクの順（それ以外は後ろ）に並べて返す
+func sogoKeys(m map[string]int) []string {
+	var keys []string
+	for _, s := range sogoOrder {
+		if _, ok := m[s]; ok {
+			keys = append(keys, s)
+		}
+	}
+	var others []string
+	for s := range m {
+		if !contains(sogoOrder, s) {
+			others = append(others, s)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
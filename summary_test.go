@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestMapLayoutExclusions(t *testing.T) {
+	resetState(t)
+
+	l := cancerLayout{
+		name:   "テストがん検診",
+		filter: func(rec []string) bool { return rec[10] != "" },
+		result: func(rec []string) string { return "1" },
+	}
+	rec := func(bango string, kekka string) []string {
+		return testRec(bango, map[int]string{10: kekka})
+	}
+	inRecs := testInput(rec("101", "Ａ"), rec("102", ""), rec("", "Ａ"))
+
+	rows := mapLayout(l, inRecs)
+	if len(rows) != 1 {
+		t.Errorf("mapLayout rows = %d, want 1", len(rows))
+	}
+	// 受診していない人（102）と証番号が空欄の行は検診の除外に数えない
+	if len(exclusions) != 0 {
+		t.Errorf("exclusions = %v, want none", exclusions)
+	}
+}
+
+func TestMergeExclusions(t *testing.T) {
+	resetState(t)
+
+	row := func(day string, v string) []string {
+		return testRec("101", map[int]string{4: day, 11: v})
+	}
+	inRecs := testInput(row("2024-05-10", "1"), row("2024-06-01", ""), row("2024-06-15", ""))
+	mergeVisits(inRecs, "first")
+
+	if len(exclusions) != 2 || exclusions[0] != (exclusion{"入力", "複数日受診を統合"}) {
+		t.Errorf("exclusions = %v, want 2 merged rows", exclusions)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 特定健診XML（電子的な標準様式）で使うOID
+const (
+	oidHokensha   = "1.2.392.200119.6.101"  // 保険者番号
+	oidKikan      = "1.2.392.200119.6.102"  // 健診機関番号
+	oidKigo       = "1.2.392.200119.6.204"  // 被保険者証記号
+	oidBango      = "1.2.392.200119.6.205"  // 被保険者証番号
+	oidEdaban     = "1.2.392.200119.6.211"  // 被保険者証枝番
+	oidDocument   = "1.2.392.200119.6.1001" // 文書区分
+	oidService    = "1.2.392.200119.6.1002" // 実施区分
+	oidItem       = "1.2.392.200119.6.1005" // 特定健診項目コード
+	oidSection    = "1.2.392.200119.6.1010" // セクション
+	oidGender     = "1.2.392.200119.6.1104" // 性別
+	oidYesNo      = "1.2.392.200119.6.2156" // はい・いいえ
+	oidTabako     = "1.2.392.200119.6.2157" // 喫煙
+	oidTypeID     = "2.16.840.1.113883.1.3"
+	nsHL7         = "urn:hl7-org:v3"
+	nsXSI         = "http://www.w3.org/2001/XMLSchema-instance"
+	oidConfidence = "2.16.840.1.113883.5.25"
+)
+
+// 検証に使うXSD（厚労省配布のスキーマのファイル名）
+const (
+	xsdData    = "hc08_V08.xsd" // 健診情報ファイル
+	xsdIndex   = "ix08_V08.xsd" // 交換用基本情報ファイル
+	xsdSummary = "su08_V08.xsd" // 集計情報ファイル
+)
+
+// xmlItem は健診データの１列と特定健診XMLの項目の対応
+type xmlItem struct {
+	Col        int               // 健診データの列
+	Code       string            // 項目コード（JLAC10を元にした17桁）
+	Type       string            // 値の型 PQ:数値 CD:コード ST:文字
+	Unit       string            // PQ の単位
+	CodeSystem string            // CD の値のコード表
+	Codes      map[string]string // CD の健診データの値 → XMLの値（nil ならそのまま）
+}
+
+// xmlItems は検査項目マスタに無い、特定健診XMLに出力する項目（医師の診断・問診）
+var xmlItems = []xmlItem{
+	{Col: 67, Code: "9N511000000000049", Type: "ST"},
+	{Col: 68, Code: "9N516000000000049", Type: "ST"},
+	{Col: 76, Code: "9N701000000000011", Type: "CD", CodeSystem: oidYesNo},
+	{Col: 77, Code: "9N706000000000011", Type: "CD", CodeSystem: oidYesNo},
+	{Col: 78, Code: "9N711000000000011", Type: "CD", CodeSystem: oidYesNo},
+	{Col: 83, Code: "9N736000000000011", Type: "CD", CodeSystem: oidTabako},
+}
+
+type cdaID struct {
+	Root      string `xml:"root,attr"`
+	Extension string `xml:"extension,attr,omitempty"`
+}
+
+type cdaCode struct {
+	Code        string `xml:"code,attr"`
+	CodeSystem  string `xml:"codeSystem,attr,omitempty"`
+	DisplayName string `xml:"displayName,attr,omitempty"`
+}
+
+// cdaAmount は金額（MO型）
+type cdaAmount struct {
+	Value    string `xml:"value,attr"`
+	Currency string `xml:"currency,attr"`
+}
+
+type cdaValue struct {
+	Type       string `xml:"xsi:type,attr,omitempty"`
+	Value      string `xml:"value,attr,omitempty"`
+	Unit       string `xml:"unit,attr,omitempty"`
+	Code       string `xml:"code,attr,omitempty"`
+	CodeSystem string `xml:"codeSystem,attr,omitempty"`
+	Text       string `xml:",chardata"`
+}
+
+type cdaPatientRole struct {
+	IDs    []cdaID  `xml:"id"`
+	Name   string   `xml:"patient>name"`
+	Gender cdaCode  `xml:"patient>administrativeGenderCode"`
+	Birth  cdaValue `xml:"patient>birthTime"`
+}
+
+type cdaObservation struct {
+	ClassCode string   `xml:"classCode,attr"`
+	MoodCode  string   `xml:"moodCode,attr"`
+	Code      cdaCode  `xml:"code"`
+	Value     cdaValue `xml:"value"`
+}
+
+type cdaEntry struct {
+	Observation cdaObservation `xml:"observation"`
+}
+
+type cdaSection struct {
+	Code    cdaCode    `xml:"code"`
+	Title   string     `xml:"title"`
+	Entries []cdaEntry `xml:"entry"`
+}
+
+// cdaDocument は受診者１人分の健診情報ファイル（HL7 CDA）
+type cdaDocument struct {
+	XMLName         xml.Name       `xml:"urn:hl7-org:v3 ClinicalDocument"`
+	XSI             string         `xml:"xmlns:xsi,attr"`
+	SchemaLocation  string         `xml:"xsi:schemaLocation,attr"`
+	TypeID          cdaID          `xml:"typeId"`
+	ID              cdaID          `xml:"id"`
+	Code            cdaCode        `xml:"code"`
+	EffectiveTime   cdaValue       `xml:"effectiveTime"`
+	Confidentiality cdaCode        `xml:"confidentialityCode"`
+	Patient         cdaPatientRole `xml:"recordTarget>patientRole"`
+	AuthorTime      cdaValue       `xml:"author>time"`
+	Author          cdaID          `xml:"author>assignedAuthor>id"`
+	Custodian       cdaID          `xml:"custodian>assignedCustodian>representedCustodianOrganization>id"`
+	ServiceCode     cdaCode        `xml:"documentationOf>serviceEvent>code"`
+	ServiceTime     cdaValue       `xml:"documentationOf>serviceEvent>effectiveTime"`
+	Section         cdaSection     `xml:"component>structuredBody>component>section"`
+}
+
+// xmlIndex は交換用基本情報ファイル（index.xml）
+type xmlIndex struct {
+	XMLName         xml.Name `xml:"urn:hl7-org:v3 index"`
+	XSI             string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation  string   `xml:"xsi:schemaLocation,attr"`
+	InteractionType cdaCode  `xml:"interactionType"`
+	CreationTime    cdaValue `xml:"creationTime"`
+	Sender          cdaID    `xml:"sender>id"`
+	Receiver        cdaID    `xml:"receiver>id"`
+	ServiceEvent    cdaCode  `xml:"serviceEventType"`
+	TotalRecord     cdaValue `xml:"totalRecordCount"`
+}
+
+// xmlSummary は集計情報ファイル（summary.xml）
+type xmlSummary struct {
+	XMLName        xml.Name  `xml:"urn:hl7-org:v3 summary"`
+	XSI            string    `xml:"xmlns:xsi,attr"`
+	SchemaLocation string    `xml:"xsi:schemaLocation,attr"`
+	ServiceEvent   cdaCode   `xml:"serviceEventType"`
+	TotalSubject   cdaValue  `xml:"totalSubjectCount"`
+	TotalCost      cdaAmount `xml:"totalCostAmount"`            // 健診金額の合計
+	TotalPayment   cdaAmount `xml:"totalPaymentAmount"`         // 窓口負担金額の合計
+	TotalClaim     cdaAmount `xml:"totalClaimAmount"`           // 請求金額の合計
+	TotalOther     cdaAmount `xml:"totalPaymentByOtherProgram"` // 他制度負担金額の合計
+}
+
+// writeXML は健診データの出力行を特定健診XMLのZIPファイルにする
+// ZIPの中は index.xml・summary.xml と DATA フォルダの受診者ごとのファイル
+func writeXML(filename string, rows []outRow) {
+	if len(rows) == 0 {
+		return
+	}
+
+	day := runTime
+
+	tmp, err := os.MkdirTemp("", "tokutei")
+	failOnError(err)
+	defer os.RemoveAll(tmp)
+	failOnError(os.Mkdir(filepath.Join(tmp, "DATA"), 0777))
+
+	// 受診者ごとの健診情報ファイル
+	files := []string{"index.xml", "summary.xml"}
+	items := cdaItems()
+	cost, claim := 0, 0
+	for I, row := range rows {
+		cRec := row.CRec
+		name := fmt.Sprintf("DATA/h%s%s%05d.xml", conf.XML.Kikan, day.Format("20060102"), I+1)
+		saveXML(filepath.Join(tmp, name), cdaRecord(cRec, items, I+1, day))
+		files = append(files, name)
+
+		v, _ := strconv.Atoi(cRec[24])
+		cost += v
+		v, _ = strconv.Atoi(cRec[26])
+		claim += v
+	}
+
+	saveXML(filepath.Join(tmp, "index.xml"), xmlIndex{
+		XSI:             nsXSI,
+		SchemaLocation:  nsHL7 + " ../XSD/" + xsdIndex,
+		InteractionType: cdaCode{Code: "1", CodeSystem: oidDocument},
+		CreationTime:    cdaValue{Value: day.Format("20060102")},
+		Sender:          cdaID{Root: oidKikan, Extension: conf.XML.Kikan},
+		Receiver:        cdaID{Root: oidHokensha, Extension: conf.XML.Hokensha},
+		ServiceEvent:    cdaCode{Code: "1", CodeSystem: oidService},
+		TotalRecord:     cdaValue{Value: strconv.Itoa(len(rows))},
+	})
+
+	saveXML(filepath.Join(tmp, "summary.xml"), xmlSummary{
+		XSI:            nsXSI,
+		SchemaLocation: nsHL7 + " ../XSD/" + xsdSummary,
+		ServiceEvent:   cdaCode{Code: "1", CodeSystem: oidService},
+		TotalSubject:   cdaValue{Value: strconv.Itoa(len(rows))},
+		TotalCost:      cdaAmount{Value: strconv.Itoa(cost), Currency: "JPY"},
+		TotalPayment:   cdaAmount{Value: strconv.Itoa(cost - claim), Currency: "JPY"},
+		TotalClaim:     cdaAmount{Value: strconv.Itoa(claim), Currency: "JPY"},
+		TotalOther:     cdaAmount{Value: "0", Currency: "JPY"},
+	})
+
+	validateXML(tmp, files)
+
+	zipName, _ := filepath.Split(filename)
+	zipName = outputFile(zipName, "特定健診XMLデータ", ".zip")
+	zipFiles(zipName, tmp, files)
+	logInfo("特定健診XML", "path", zipName, "count", len(rows))
+}
+
+// cdaItems は健診データの列の順に特定健診XMLに出力する項目を返す
+// 検査項目は検査項目マスタから、それ以外は xmlItems から作る
+func cdaItems() []xmlItem {
+	var items []xmlItem
+	for I, c := range (kenshinLayout{}).Columns() {
+		if l, ok := labItem(c.Name); ok {
+			items = append(items, xmlItem{
+				Col:        I,
+				Code:       l.JLAC10,
+				Type:       l.Type,
+				Unit:       l.Unit,
+				CodeSystem: l.CodeSystem,
+				Codes:      l.Codes,
+			})
+			continue
+		}
+
+		for _, item := range xmlItems {
+			if item.Col == I {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// cdaRecord は健診データの出力行１件を健診情報ファイルの内容にする
+func cdaRecord(cRec []string, items []xmlItem, seq int, day time.Time) cdaDocument {
+	cols := kenshinLayout{}.Columns()
+
+	var entries []cdaEntry
+	for _, item := range items {
+		s := cRec[item.Col]
+		if s == "" || s == "err" {
+			continue
+		}
+
+		v := cdaValue{Type: item.Type}
+		switch item.Type {
+		case "PQ":
+			v.Value = s
+			v.Unit = item.Unit
+		case "CD":
+			if item.Codes != nil {
+				s = item.Codes[s]
+			}
+			v.Code = s
+			v.CodeSystem = item.CodeSystem
+		default:
+			v.Text = s
+		}
+
+		entries = append(entries, cdaEntry{cdaObservation{
+			ClassCode: "OBS",
+			MoodCode:  "EVN",
+			Code:      cdaCode{Code: item.Code, CodeSystem: oidItem, DisplayName: cols[item.Col].Name},
+			Value:     v,
+		}})
+	}
+
+	ids := []cdaID{
+		{Root: oidHokensha, Extension: conf.XML.Hokensha},
+		{Root: oidKigo, Extension: cRec[3]},
+		{Root: oidBango, Extension: cRec[4]},
+	}
+	if cRec[7] != "" {
+		ids = append(ids, cdaID{Root: oidEdaban, Extension: cRec[7]})
+	}
+
+	return cdaDocument{
+		XSI:             nsXSI,
+		SchemaLocation:  nsHL7 + " ../XSD/" + xsdData,
+		TypeID:          cdaID{Root: oidTypeID, Extension: "POCD_HD000040"},
+		ID:              cdaID{Root: oidKikan + "." + conf.XML.Kikan, Extension: strconv.Itoa(seq)},
+		Code:            cdaCode{Code: "10", CodeSystem: oidDocument},
+		EffectiveTime:   cdaValue{Value: day.Format("20060102")},
+		Confidentiality: cdaCode{Code: "N", CodeSystem: oidConfidence},
+		Patient: cdaPatientRole{
+			IDs:    ids,
+			Name:   cRec[9],
+			Gender: cdaCode{Code: cRec[10], CodeSystem: oidGender},
+			Birth:  cdaValue{Value: strings.Replace(cRec[11], "/", "", -1)},
+		},
+		AuthorTime:  cdaValue{Value: day.Format("20060102")},
+		Author:      cdaID{Root: oidKikan, Extension: conf.XML.Kikan},
+		Custodian:   cdaID{Root: oidKikan, Extension: conf.XML.Kikan},
+		ServiceCode: cdaCode{Code: "1", CodeSystem: oidService},
+		ServiceTime: cdaValue{Value: strings.Replace(cRec[2], "/", "", -1)},
+		Section: cdaSection{
+			Code:    cdaCode{Code: "01010", CodeSystem: oidSection},
+			Title:   "検査・問診結果セクション",
+			Entries: entries,
+		},
+	}
+}
+
+// saveXML はXMLファイル（UTF-8）を書き出す
+func saveXML(path string, v interface{}) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	failOnError(err)
+	err = os.WriteFile(path, append([]byte(xml.Header), b...), 0666)
+	failOnError(err)
+}
+
+// validateXML は出力したXMLファイルを検証する
+// 全ファイルの整形式・名前空間（urn:hl7-org:v3）・ルート要素を調べ、
+// 設定の schema にXSDがあり xmllint が見つかればスキーマでも検証する。結果はログに出力する
+func validateXML(dir string, files []string) {
+	ng := 0
+	for _, f := range files {
+		if err := checkXML(filepath.Join(dir, f), xmlRoot(f)); err != nil {
+			ng++
+			logWarn("特定健診XML検証エラー", "file", f, "detail", err.Error())
+		}
+	}
+
+	schema := 0
+	if xmllint := findXmllint(); conf.XML.Schema != "" && xmllint != "" {
+		for _, f := range files {
+			xsd := filepath.Join(conf.XML.Schema, xmlSchema(f))
+			if _, err := os.Stat(xsd); err != nil {
+				logWarn("XSDがありません", "path", xsd)
+				break
+			}
+
+			out, err := exec.Command(xmllint, "--noout", "--schema", xsd, filepath.Join(dir, f)).CombinedOutput()
+			if err != nil {
+				ng++
+				logWarn("特定健診XMLスキーマ検証エラー", "file", f, "detail", string(out))
+			}
+			schema++
+		}
+	} else if conf.XML.Schema != "" {
+		logWarn("xmllint が無いためXSDでの検証はしません（整形式・名前空間だけ検証）")
+	}
+	logInfo("特定健診XML検証", "files", len(files), "schema", schema, "errors", ng)
+}
+
+// checkXML はXMLファイルが整形式で、ルート要素が HL7 の名前空間の root か調べる
+func checkXML(path string, root string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(f)
+	first := true
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if e, ok := t.(xml.StartElement); ok && first {
+			first = false
+			if e.Name.Space != nsHL7 || e.Name.Local != root {
+				return fmt.Errorf("ルート要素が {%s}%s です（{%s}%s のはず）", e.Name.Space, e.Name.Local, nsHL7, root)
+			}
+		}
+	}
+	if first {
+		return fmt.Errorf("要素がありません")
+	}
+	return nil
+}
+
+// xmlRoot はZIPの中のファイルのルート要素名
+func xmlRoot(f string) string {
+	switch f {
+	case "index.xml":
+		return "index"
+	case "summary.xml":
+		return "summary"
+	}
+	return "ClinicalDocument"
+}
+
+// xmlSchema はZIPの中のファイルを検証するXSDのファイル名
+func xmlSchema(f string) string {
+	switch f {
+	case "index.xml":
+		return xsdIndex
+	case "summary.xml":
+		return xsdSummary
+	}
+	return xsdData
+}
+
+// findXmllint は xmllint を設定・exe と同じフォルダ・PATH の順に探す（無ければ空欄）
+// Windows の端末には xmllint が無いことが多いため、xmllint.exe を exe と同じフォルダに置けば使う
+func findXmllint() string {
+	if conf.XML.Xmllint != "" {
+		return conf.XML.Xmllint
+	}
+	for _, name := range []string{"xmllint.exe", "xmllint"} {
+		path := filepath.Join(filepath.Dir(configPath()), name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath("xmllint"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// zipFiles はフォルダのファイルをZIPファイルにまとめる
+func zipFiles(zipName string, dir string, files []string) {
+	out, err := os.Create(zipName)
+	failOnError(err)
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		failOnError(err)
+		// 日時が無いと 1980年 や 0年 として展開されるため作成日時を入れる
+		zf, err := w.CreateHeader(&zip.FileHeader{Name: f, Method: zip.Deflate, Modified: runTime})
+		failOnError(err)
+		_, err = zf.Write(b)
+		failOnError(err)
+	}
+	failOnError(w.Close())
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// go test -run TestCdaRecordGolden -update で testdata の期待値を作り直す
+var update = flag.Bool("update", false, "testdata の期待値を更新する")
+
+// xmlTestRow は特定健診XMLのテスト用の健診データの出力行
+func xmlTestRow() []string {
+	cols := kenshinLayout{}.Columns()
+	cRec := make([]string, len(cols))
+	values := map[string]string{
+		"受診日":     "2024/05/10",
+		"事業所記号":   "3025",
+		"証番号":     "101",
+		"カナ氏名":    "ｹﾝﾎﾟ ﾀﾛｳ",
+		"性別":      "1",
+		"生年月日":    "1975/01/01",
+		"健診金額":    "7300",
+		"請求金額":    "7300",
+		"身長":      "170.5",
+		"体重":      "65.0",
+		"尿糖":      "-",
+		"血圧（収縮期）": "120",
+	}
+	for I, c := range cols {
+		cRec[I] = values[c.Name]
+	}
+	return cRec
+}
+
+func TestCdaRecordGolden(t *testing.T) {
+	resetState(t)
+	conf.XML.Kikan, conf.XML.Hokensha = "1234567890", "06130000"
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), "h.xml")
+	saveXML(path, cdaRecord(xmlTestRow(), cdaItems(), 1, day))
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "cda.golden.xml")
+	if *update {
+		if err := os.WriteFile(golden, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("健診情報ファイルが期待値と違う（-update で更新）\n%s", got)
+	}
+
+	if err := checkXML(path, "ClinicalDocument"); err != nil {
+		t.Errorf("checkXML: %v", err)
+	}
+
+	// XSD（環境変数 NWTOSHOKUIN_XSD のフォルダ）と xmllint があればスキーマでも検証する
+	xsd := os.Getenv("NWTOSHOKUIN_XSD")
+	xmllint, err := exec.LookPath("xmllint")
+	if xsd == "" || err != nil {
+		t.Skip("XSD・xmllint が無いためスキーマでは検証しない")
+	}
+	out, err := exec.Command(xmllint, "--noout", "--schema", filepath.Join(xsd, xsdData), path).CombinedOutput()
+	if err != nil {
+		t.Errorf("xmllint: %v\n%s", err, out)
+	}
+}
+
+func TestCheckXML(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		xml  string
+		root string
+		ok   bool
+	}{
+		{`<index xmlns="urn:hl7-org:v3"><totalRecordCount value="1"/></index>`, "index", true},
+		{`<index><totalRecordCount value="1"/></index>`, "index", false},             // 名前空間なし
+		{`<summary xmlns="urn:hl7-org:v3"></summary>`, "index", false},               // ルート要素が違う
+		{`<index xmlns="urn:hl7-org:v3"><totalRecordCount></index>`, "index", false}, // 整形式でない
+		{``, "index", false},
+	}
+	for I, tt := range tests {
+		path := filepath.Join(dir, "t.xml")
+		if err := os.WriteFile(path, []byte(tt.xml), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkXML(path, tt.root); (err == nil) != tt.ok {
+			t.Errorf("%d: checkXML(%q) = %v, want ok %v", I, tt.xml, err, tt.ok)
+		}
+	}
+}
+
+func TestWriteXMLZip(t *testing.T) {
+	resetState(t)
+	conf.XML.Kikan, conf.XML.Hokensha = "1234567890", "06130000"
+
+	dir := t.TempDir()
+	writeXML(filepath.Join(dir, "x.xlsx"), []outRow{{CRec: xmlTestRow()}})
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.zip"))
+	if len(files) != 1 {
+		t.Fatalf("zip files = %v", files)
+	}
+	r, err := zip.OpenReader(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if len(r.File) != 3 {
+		t.Errorf("zip entries = %d, want 3", len(r.File))
+	}
+	for _, f := range r.File {
+		if f.Modified.Year() < 2000 {
+			t.Errorf("%s: modified = %v", f.Name, f.Modified)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		b.ReadFrom(rc)
+		rc.Close()
+
+		path := filepath.Join(t.TempDir(), "e.xml")
+		if err := os.WriteFile(path, b.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkXML(path, xmlRoot(f.Name)); err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+		}
+	}
+}